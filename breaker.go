@@ -0,0 +1,347 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package serialkey
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	// BreakerFailures is the default number of the consecutive failures
+	// opening the circuit breaker.
+	BreakerFailures = 5
+	// BreakerCooldown is the default duration of the open state
+	// of the circuit breaker before the probe call.
+	BreakerCooldown = 5 * time.Second
+)
+
+// BreakerState is the state of the circuit breaker.
+type BreakerState int
+
+const (
+	// BreakerClosed passes the calls to the underlying chain.
+	BreakerClosed BreakerState = iota
+	// BreakerOpen fails the calls fast until the cooldown expires.
+	BreakerOpen
+	// BreakerHalfOpen passes the single probe call to the underlying chain
+	// and fails the rest of the calls fast until the probe call returns.
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("BreakerState(%d)", int(s))
+	}
+}
+
+// NewBreaker returns the chain protecting the chain by the circuit breaker.
+func NewBreaker(chain Chain, opts ...BreakerOption) *Breaker {
+	cfg := BreakerConfiguration{
+		failures: BreakerFailures,
+		cooldown: BreakerCooldown,
+	}
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	var inflight chan struct{}
+	if cfg.maxInFlight > 0 {
+		inflight = make(chan struct{}, cfg.maxInFlight)
+	}
+
+	return &Breaker{
+		chain:    chain,
+		failures: cfg.failures,
+		latency:  cfg.latency,
+		cooldown: cfg.cooldown,
+		logger:   cfg.logger,
+		inflight: inflight,
+	}
+}
+
+// Breaker is the serialkeys keychain failing the calls fast
+// by the ErrUnavailable error while the underlying chain is unhealthy.
+//
+// The breaker opens after the consecutive failures of the calls,
+// the calls slower than the latency and the calls exceeded the deadline
+// count as the failures as well, the calls canceled by the caller
+// and the invalid requests do not count.
+// After the cooldown the breaker half-opens and passes the single probe call,
+// the successful probe closes the breaker and the failed one opens it again.
+// The breaker also caps the number of the concurrent calls in flight,
+// the calls above the cap fail fast instead of waiting for the backend.
+type Breaker struct {
+	sync.Mutex
+	chain    Chain
+	failures int
+	latency  time.Duration
+	cooldown time.Duration
+	logger   *slog.Logger
+	inflight chan struct{}
+	state    BreakerState
+	failed   int
+	opened   time.Time
+}
+
+// Next for the passed key name returns an value guaranteed to be greater
+// than the value returned for the same key name passed at the time
+// of previous call of the next method or the forward method.
+// The next method is thread safe.
+func (chain *Breaker) Next(ctx context.Context, key string) (int64, error) {
+	var value int64
+
+	err := chain.call(ctx, func() error {
+		var err error
+		value, err = chain.chain.Next(ctx, key)
+		return err
+	})
+
+	return value, err
+}
+
+// NextN for the passed key name reserves the count of values
+// and returns the last one of them.
+// The next N method is thread safe.
+func (chain *Breaker) NextN(ctx context.Context, key string, count int64) (int64, error) {
+	c, ok := chain.chain.(NextNer)
+	if !ok {
+		return 0, fmt.Errorf("next n: %w", ErrNotImplemented)
+	}
+
+	var value int64
+
+	err := chain.call(ctx, func() error {
+		var err error
+		value, err = c.NextN(ctx, key, count)
+		return err
+	})
+
+	return value, err
+}
+
+// Last for the passed key name returns the value returned for
+// the same key name passed at the time of previous call
+// of the next method or the forward method.
+// The last method is thread safe.
+func (chain *Breaker) Last(ctx context.Context, key string) (int64, error) {
+	var value int64
+
+	err := chain.call(ctx, func() error {
+		var err error
+		value, err = chain.chain.Last(ctx, key)
+		return err
+	})
+
+	return value, err
+}
+
+// Forward for the passed key name returns an value guaranteed
+// to be greater or equal to the target value and guaranteed to be greater
+// than the value returned for the same key name passed at the time
+// of previous call of the forward method or the next method.
+// The forward method is thread safe.
+func (chain *Breaker) Forward(ctx context.Context, key string, target int64) (int64, error) {
+	var value int64
+
+	err := chain.call(ctx, func() error {
+		var err error
+		value, err = chain.chain.Forward(ctx, key, target)
+		return err
+	})
+
+	return value, err
+}
+
+// State returns the current state of the circuit breaker.
+// The state method is thread safe.
+func (chain *Breaker) State() BreakerState {
+	chain.Lock()
+	defer chain.Unlock()
+
+	if chain.state == BreakerOpen && time.Since(chain.opened) >= chain.cooldown {
+		return BreakerHalfOpen
+	}
+
+	return chain.state
+}
+
+// Health checks the health of the underlying chain
+// regardless of the state of the breaker.
+// The health method is thread safe.
+func (chain *Breaker) Health(ctx context.Context) error {
+	return Health(ctx, chain.chain)
+}
+
+// Durable reports whether the underlying chain is durable.
+// The durable method is thread safe.
+func (chain *Breaker) Durable() bool {
+	return Durable(chain.chain)
+}
+
+// Close closes the underlying chain.
+// The close method is thread safe.
+func (chain *Breaker) Close() error {
+	return chain.chain.Close()
+}
+
+// call calls the function if the breaker and the in-flight cap allow it.
+func (chain *Breaker) call(ctx context.Context, f func() error) error {
+	if chain.inflight != nil {
+		select {
+		case chain.inflight <- struct{}{}:
+			defer func() { <-chain.inflight }()
+		default:
+			return fmt.Errorf("too many calls in flight: %w", ErrUnavailable)
+		}
+	}
+
+	probe, err := chain.allow()
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	err = f()
+	chain.done(ctx, probe, err, time.Since(start))
+
+	return err
+}
+
+// allow returns the error if the breaker rejects the call
+// and returns true if the call is the probe call.
+func (chain *Breaker) allow() (bool, error) {
+	chain.Lock()
+	defer chain.Unlock()
+
+	switch chain.state {
+	case BreakerOpen:
+		if time.Since(chain.opened) < chain.cooldown {
+			return false, fmt.Errorf("circuit breaker is open: %w", ErrUnavailable)
+		}
+
+		chain.state = BreakerHalfOpen
+
+		return true, nil
+
+	case BreakerHalfOpen:
+		return false, fmt.Errorf("circuit breaker is half-open: %w", ErrUnavailable)
+
+	default:
+		return false, nil
+	}
+}
+
+// done records the result of the call.
+func (chain *Breaker) done(ctx context.Context, probe bool, err error, duration time.Duration) {
+	slow := chain.latency > 0 && duration >= chain.latency
+
+	// The calls canceled by the caller tell nothing about the backend,
+	// unlike the calls exceeded the deadline waiting for the backend.
+	canceled := err != nil && !slow && errors.Is(ctx.Err(), context.Canceled)
+
+	failed := slow || !canceled &&
+		err != nil && !errors.Is(err, ErrInvalidRequest) && !errors.Is(err, ErrNotImplemented)
+
+	chain.Lock()
+	defer chain.Unlock()
+
+	switch {
+	case probe && canceled:
+		// Let the next call probe the backend.
+		chain.state = BreakerOpen
+
+	case probe && failed:
+		chain.open(err, duration)
+
+	case probe:
+		chain.state, chain.failed = BreakerClosed, 0
+		chain.log(slog.LevelInfo, "circuit breaker closed")
+
+	case chain.state != BreakerClosed || canceled:
+		// The calls started before the breaker opened are not counted.
+
+	case failed:
+		chain.failed++
+		if chain.failures > 0 && chain.failed >= chain.failures {
+			chain.open(err, duration)
+		}
+
+	default:
+		chain.failed = 0
+	}
+}
+
+// open opens the breaker, the caller must hold the lock.
+func (chain *Breaker) open(err error, duration time.Duration) {
+	chain.state, chain.failed, chain.opened = BreakerOpen, 0, time.Now()
+
+	attrs := []slog.Attr{slog.Duration("duration", duration), slog.Duration("cooldown", chain.cooldown)}
+	if err != nil {
+		attrs = append(attrs, slog.Any("error", err))
+	}
+
+	chain.log(slog.LevelWarn, "circuit breaker opened", attrs...)
+}
+
+func (chain *Breaker) log(level slog.Level, msg string, attrs ...slog.Attr) {
+	if chain.logger == nil {
+		return
+	}
+	chain.logger.LogAttrs(context.Background(), level, msg, attrs...)
+}
+
+// BreakerOption changes configuration.
+type BreakerOption func(*BreakerConfiguration)
+
+// BreakerConfiguration holds values changeable by options.
+type BreakerConfiguration struct {
+	failures    int
+	latency     time.Duration
+	cooldown    time.Duration
+	maxInFlight int
+	logger      *slog.Logger
+}
+
+// BreakerWithFailures sets the number of the consecutive failures
+// opening the breaker, the zero never opens the breaker.
+func BreakerWithFailures(failures int) BreakerOption {
+	return func(cfg *BreakerConfiguration) { cfg.failures = failures }
+}
+
+// BreakerWithLatency sets the duration of the call counted as the failure,
+// the zero latency disables the counting of the slow calls.
+func BreakerWithLatency(latency time.Duration) BreakerOption {
+	return func(cfg *BreakerConfiguration) { cfg.latency = latency }
+}
+
+// BreakerWithCooldown sets the duration of the open state
+// before the probe call.
+func BreakerWithCooldown(cooldown time.Duration) BreakerOption {
+	return func(cfg *BreakerConfiguration) { cfg.cooldown = cooldown }
+}
+
+// BreakerWithMaxInFlight sets the maximum number of the concurrent calls
+// of the underlying chain, the zero does not limit the calls.
+func BreakerWithMaxInFlight(n int) BreakerOption {
+	return func(cfg *BreakerConfiguration) { cfg.maxInFlight = n }
+}
+
+// BreakerWithLogger sets the logger of the state changes of the breaker.
+func BreakerWithLogger(logger *slog.Logger) BreakerOption {
+	return func(cfg *BreakerConfiguration) { cfg.logger = logger }
+}
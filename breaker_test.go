@@ -0,0 +1,342 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package serialkey_test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pfmt/serialkey"
+	"github.com/pfmt/serialkey/internal/serialkeytest"
+	"github.com/pfmt/serialkey/server"
+)
+
+func TestBreaker(t *testing.T) {
+	chain := serialkey.NewBreaker(serialkey.NewLocal(localOpt))
+	serialkeytest.Next(t, chain)
+	closer.add(chain.Close)
+}
+
+func TestBreakerStates(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	backend := &downChain{Chain: serialkey.NewLocal(localOpt)}
+	backend.down.Store(true)
+
+	chain := serialkey.NewBreaker(
+		backend,
+		serialkey.BreakerWithFailures(3),
+		serialkey.BreakerWithCooldown(50*time.Millisecond),
+	)
+	t.Cleanup(func() { _ = chain.Close() })
+
+	for i := 0; i < 3; i++ {
+		_, err := chain.Next(ctx, "foo")
+		if !errors.Is(err, errDown) {
+			t.Fatalf("want backend error %d, got: %v", i, err)
+		}
+	}
+
+	if chain.State() != serialkey.BreakerOpen {
+		t.Fatalf("want open breaker, got: %s", chain.State())
+	}
+
+	_, err := chain.Next(ctx, "foo")
+	if !errors.Is(err, serialkey.ErrUnavailable) {
+		t.Errorf("want unavailable error, got: %v", err)
+	}
+
+	if calls := backend.calls.Load(); calls != 3 {
+		t.Errorf("want backend calls of the open breaker: 3, got: %d", calls)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+
+	if chain.State() != serialkey.BreakerHalfOpen {
+		t.Fatalf("want half-open breaker, got: %s", chain.State())
+	}
+
+	_, err = chain.Next(ctx, "foo")
+	if !errors.Is(err, errDown) {
+		t.Fatalf("want failed probe, got: %v", err)
+	}
+
+	if chain.State() != serialkey.BreakerOpen {
+		t.Fatalf("want reopened breaker, got: %s", chain.State())
+	}
+
+	time.Sleep(60 * time.Millisecond)
+
+	backend.down.Store(false)
+
+	_, err = chain.Next(ctx, "foo")
+	if err != nil {
+		t.Fatalf("want successful probe, got: %s", err)
+	}
+
+	if chain.State() != serialkey.BreakerClosed {
+		t.Errorf("want closed breaker, got: %s", chain.State())
+	}
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	backend := newBlockChain(serialkey.NewLocal(localOpt))
+	backend.block.Store(false)
+	backend.down.Store(true)
+
+	chain := serialkey.NewBreaker(backend, serialkey.BreakerWithFailures(1), serialkey.BreakerWithCooldown(time.Millisecond))
+	t.Cleanup(func() { _ = chain.Close() })
+
+	_, err := chain.Next(ctx, "foo")
+	if !errors.Is(err, errDown) {
+		t.Fatalf("want backend error, got: %v", err)
+	}
+
+	time.Sleep(2 * time.Millisecond)
+
+	backend.down.Store(false)
+	backend.block.Store(true)
+
+	probe := make(chan error)
+
+	go func() {
+		_, err := chain.Next(ctx, "foo")
+		probe <- err
+	}()
+
+	<-backend.started
+
+	_, err = chain.Next(ctx, "foo")
+	if !errors.Is(err, serialkey.ErrUnavailable) {
+		t.Errorf("want unavailable error while probing, got: %v", err)
+	}
+
+	close(backend.release)
+
+	err = <-probe
+	if err != nil {
+		t.Fatalf("probe: %s", err)
+	}
+
+	backend.block.Store(false)
+
+	_, err = chain.Next(ctx, "foo")
+	if err != nil {
+		t.Errorf("want closed breaker after the probe, got: %s", err)
+	}
+}
+
+func TestBreakerLatency(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	chain := serialkey.NewBreaker(
+		slowChain{Chain: serialkey.NewLocal(localOpt), sleep: 10 * time.Millisecond},
+		serialkey.BreakerWithFailures(2),
+		serialkey.BreakerWithLatency(5*time.Millisecond),
+		serialkey.BreakerWithCooldown(time.Hour),
+	)
+	t.Cleanup(func() { _ = chain.Close() })
+
+	for i := 0; i < 2; i++ {
+		_, err := chain.Next(ctx, "foo")
+		if err != nil {
+			t.Fatalf("want slow value %d, got: %s", i, err)
+		}
+	}
+
+	_, err := chain.Next(ctx, "foo")
+	if !errors.Is(err, serialkey.ErrUnavailable) {
+		t.Errorf("want unavailable error after the slow calls, got: %v", err)
+	}
+}
+
+func TestBreakerDeadline(t *testing.T) {
+	t.Parallel()
+
+	chain := serialkey.NewBreaker(
+		ctxChain{Chain: serialkey.NewLocal(localOpt)},
+		serialkey.BreakerWithFailures(2),
+		serialkey.BreakerWithLatency(5*time.Millisecond),
+		serialkey.BreakerWithCooldown(time.Hour),
+	)
+	t.Cleanup(func() { _ = chain.Close() })
+
+	for i := 0; i < 2; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+
+		_, err := chain.Next(ctx, "foo")
+		cancel()
+
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("want deadline exceeded error %d, got: %v", i, err)
+		}
+	}
+
+	if chain.State() != serialkey.BreakerOpen {
+		t.Errorf("want open breaker after the deadlines, got: %s", chain.State())
+	}
+}
+
+func TestBreakerIgnoredErrors(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	chain := serialkey.NewBreaker(snowflakeChain(t), serialkey.BreakerWithFailures(1))
+	t.Cleanup(func() { _ = chain.Close() })
+
+	_, err := chain.NextN(ctx, "foo", 42)
+	if !errors.Is(err, serialkey.ErrNotImplemented) {
+		t.Fatalf("want not implemented error, got: %v", err)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	backend := &downChain{Chain: serialkey.NewLocal(localOpt)}
+	backend.down.Store(true)
+
+	canceledChain := serialkey.NewBreaker(backend, serialkey.BreakerWithFailures(1))
+
+	_, err = canceledChain.Next(canceled, "foo")
+	if !errors.Is(err, errDown) {
+		t.Fatalf("want backend error, got: %v", err)
+	}
+
+	for _, c := range []*serialkey.Breaker{chain, canceledChain} {
+		if c.State() != serialkey.BreakerClosed {
+			t.Errorf("want closed breaker, got: %s", c.State())
+		}
+	}
+}
+
+func TestBreakerMaxInFlight(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	backend := newBlockChain(serialkey.NewLocal(localOpt))
+
+	chain := serialkey.NewBreaker(backend, serialkey.BreakerWithMaxInFlight(2))
+	t.Cleanup(func() { _ = chain.Close() })
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			_, err := chain.Next(ctx, "foo")
+			if err != nil {
+				t.Errorf("next in flight: %s", err)
+			}
+		}()
+
+		<-backend.started
+	}
+
+	_, err := chain.Next(ctx, "foo")
+	if !errors.Is(err, serialkey.ErrUnavailable) {
+		t.Errorf("want unavailable error above the cap, got: %v", err)
+	}
+
+	backend.block.Store(false)
+	close(backend.release)
+	wg.Wait()
+
+	if chain.State() != serialkey.BreakerClosed {
+		t.Errorf("want closed breaker after the load shedding, got: %s", chain.State())
+	}
+
+	_, err = chain.Next(ctx, "foo")
+	if err != nil {
+		t.Errorf("want value below the cap, got: %s", err)
+	}
+}
+
+func TestBreakerHTTPClient(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	backend := &downChain{Chain: serialkey.NewLocal(localOpt)}
+	backend.down.Store(true)
+
+	ts := httptest.NewServer(server.New(serialkey.NewBreaker(
+		backend,
+		serialkey.BreakerWithFailures(1),
+		serialkey.BreakerWithCooldown(time.Hour),
+	)))
+	defer ts.Close()
+
+	chain := serialkey.NewHTTPClient(ts.URL, serialkey.HTTPClientWithClient(ts.Client()))
+	defer chain.Close()
+
+	_, err := chain.Last(ctx, "foo")
+	if err == nil {
+		t.Fatalf("want backend error")
+	}
+
+	_, err = chain.Last(ctx, "foo")
+	if !errors.Is(err, serialkey.ErrUnavailable) {
+		t.Errorf("want unavailable error, got: %v", err)
+	}
+}
+
+// blockChain is the chain blocking the next method calls
+// while it is blocked until released.
+type blockChain struct {
+	downChain
+	block   atomic.Bool
+	started chan struct{}
+	release chan struct{}
+}
+
+func newBlockChain(chain serialkey.Chain) *blockChain {
+	c := &blockChain{started: make(chan struct{}, 10), release: make(chan struct{})}
+	c.Chain = chain
+	c.block.Store(true)
+	return c
+}
+
+func (chain *blockChain) Next(ctx context.Context, key string) (int64, error) {
+	if chain.block.Load() {
+		chain.started <- struct{}{}
+		<-chain.release
+	}
+	return chain.downChain.Next(ctx, key)
+}
+
+// ctxChain is the chain blocking the next method calls
+// until the context is done.
+type ctxChain struct {
+	serialkey.Chain
+}
+
+func (chain ctxChain) Next(ctx context.Context, _ string) (int64, error) {
+	<-ctx.Done()
+	return 0, ctx.Err()
+}
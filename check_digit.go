@@ -0,0 +1,266 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package serialkey
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// CheckDigits is the check digit algorithm.
+type CheckDigits interface {
+	// Append returns the decimal number with the appended check digits.
+	Append(number string) (string, error)
+
+	// Validate reports whether the decimal number
+	// ends with the valid check digits.
+	Validate(number string) bool
+}
+
+var (
+	// Luhn is the Luhn mod 10 algorithm, it appends one check digit.
+	Luhn CheckDigits = luhn{}
+	// Damm is the Damm algorithm, it appends one check digit.
+	Damm CheckDigits = damm{}
+	// Mod97 is the ISO 7064 mod 97-10 algorithm, it appends two check digits.
+	Mod97 CheckDigits = mod97{}
+)
+
+// NewCheckDigit returns the serialkeys keychain which appends
+// the check digits to the values returned by the underlying chain.
+func NewCheckDigit(chain Chain, algorithm CheckDigits, opts ...CheckDigitOption) *CheckDigit {
+	var cfg CheckDigitConfiguration
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return &CheckDigit{chain: chain, algorithm: algorithm, start: cfg.start}
+}
+
+// CheckDigit is the serialkeys keychain issuing values with the check digits.
+type CheckDigit struct {
+	chain     Chain
+	algorithm CheckDigits
+	start     int64
+}
+
+// Next for the passed key name returns the next value of the underlying chain
+// with the appended check digits.
+// The next method is thread safe if the underlying chain is thread safe.
+func (chain *CheckDigit) Next(ctx context.Context, key string) (int64, error) {
+	value, err := chain.chain.Next(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	return chain.append(value)
+}
+
+// Last for the passed key name returns the last value of the underlying chain
+// with the appended check digits, the value below the start number
+// of the key never issued (the start number minus one)
+// is returned unchanged.
+// The last method is thread safe if the underlying chain is thread safe.
+func (chain *CheckDigit) Last(ctx context.Context, key string) (int64, error) {
+	value, err := chain.chain.Last(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	if value < chain.start {
+		return value, nil
+	}
+	return chain.append(value)
+}
+
+// Forward forwards the underlying chain to the target value
+// passed without the check digits and returns the result
+// with the appended check digits.
+// The forward method is thread safe if the underlying chain is thread safe.
+func (chain *CheckDigit) Forward(ctx context.Context, key string, target int64) (int64, error) {
+	value, err := chain.chain.Forward(ctx, key, target)
+	if err != nil {
+		return 0, err
+	}
+	return chain.append(value)
+}
+
+// Validate reports whether the decimal number
+// ends with the valid check digits.
+func (chain *CheckDigit) Validate(number string) bool {
+	return chain.algorithm.Validate(number)
+}
+
+// Health checks the health of the underlying chain.
+// The health method is thread safe if the underlying chain is thread safe.
+func (chain *CheckDigit) Health(ctx context.Context) error {
+	return Health(ctx, chain.chain)
+}
+
+// Close closes the underlying chain.
+// The close method is thread safe if the underlying chain is thread safe.
+func (chain *CheckDigit) Close() error {
+	return chain.chain.Close()
+}
+
+func (chain *CheckDigit) append(value int64) (int64, error) {
+	if value < 0 {
+		return 0, fmt.Errorf("append check digits to negative value %d", value)
+	}
+
+	number, err := chain.algorithm.Append(strconv.FormatInt(value, 10))
+	if err != nil {
+		return 0, fmt.Errorf("append check digits to %d: %w", value, err)
+	}
+
+	result, err := strconv.ParseInt(number, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse value %d with check digits: %w", value, err)
+	}
+
+	return result, nil
+}
+
+type luhn struct{}
+
+func (luhn) Append(number string) (string, error) {
+	sum, err := luhnSum(number, true)
+	if err != nil {
+		return "", err
+	}
+	return number + strconv.Itoa((10-sum%10)%10), nil
+}
+
+func (luhn) Validate(number string) bool {
+	if len(number) < 2 {
+		return false
+	}
+	sum, err := luhnSum(number, false)
+	return err == nil && sum%10 == 0
+}
+
+// luhnSum returns the Luhn sum of the digits, doubling every second digit
+// from the right, the first from the right is doubled if the double is true.
+func luhnSum(number string, double bool) (int, error) {
+	var sum int
+
+	for i := len(number) - 1; i >= 0; i-- {
+		d, err := digit(number[i])
+		if err != nil {
+			return 0, err
+		}
+
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+
+		sum += d
+		double = !double
+	}
+
+	return sum, nil
+}
+
+// dammTable is the totally anti-symmetric quasigroup of order 10.
+var dammTable = [10][10]int{
+	{0, 3, 1, 7, 5, 9, 8, 6, 4, 2},
+	{7, 0, 9, 2, 1, 5, 4, 8, 6, 3},
+	{4, 2, 0, 6, 8, 7, 1, 3, 5, 9},
+	{1, 7, 5, 0, 9, 8, 3, 4, 2, 6},
+	{6, 1, 2, 3, 0, 4, 5, 9, 7, 8},
+	{3, 6, 7, 4, 2, 0, 9, 5, 8, 1},
+	{5, 8, 6, 9, 7, 2, 0, 1, 3, 4},
+	{8, 9, 4, 5, 3, 1, 2, 0, 7, 6},
+	{9, 4, 3, 8, 6, 7, 5, 2, 0, 1},
+	{2, 5, 8, 1, 4, 3, 6, 7, 9, 0},
+}
+
+type damm struct{}
+
+func (damm) Append(number string) (string, error) {
+	interim, err := dammInterim(number)
+	if err != nil {
+		return "", err
+	}
+	return number + strconv.Itoa(interim), nil
+}
+
+func (damm) Validate(number string) bool {
+	if len(number) < 2 {
+		return false
+	}
+	interim, err := dammInterim(number)
+	return err == nil && interim == 0
+}
+
+func dammInterim(number string) (int, error) {
+	var interim int
+
+	for i := 0; i < len(number); i++ {
+		d, err := digit(number[i])
+		if err != nil {
+			return 0, err
+		}
+		interim = dammTable[interim][d]
+	}
+
+	return interim, nil
+}
+
+type mod97 struct{}
+
+func (mod97) Append(number string) (string, error) {
+	remainder, err := mod97Remainder(number + "00")
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s%02d", number, 98-remainder), nil
+}
+
+func (mod97) Validate(number string) bool {
+	if len(number) < 3 {
+		return false
+	}
+	remainder, err := mod97Remainder(number)
+	return err == nil && remainder == 1
+}
+
+func mod97Remainder(number string) (int, error) {
+	var remainder int
+
+	for i := 0; i < len(number); i++ {
+		d, err := digit(number[i])
+		if err != nil {
+			return 0, err
+		}
+		remainder = (remainder*10 + d) % 97
+	}
+
+	return remainder, nil
+}
+
+func digit(c byte) (int, error) {
+	if c < '0' || c > '9' {
+		return 0, fmt.Errorf("invalid decimal digit %q", c)
+	}
+	return int(c - '0'), nil
+}
+
+// CheckDigitOption changes configuration.
+type CheckDigitOption func(*CheckDigitConfiguration)
+
+// CheckDigitConfiguration holds values changeable by options.
+type CheckDigitConfiguration struct {
+	start int64
+}
+
+// CheckDigitWithStart sets the start number of the underlying chain,
+// the last values below it are of the keys never issued.
+func CheckDigitWithStart(start int64) CheckDigitOption {
+	return func(cfg *CheckDigitConfiguration) { cfg.start = start }
+}
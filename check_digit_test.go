@@ -0,0 +1,150 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package serialkey_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/pfmt/serialkey"
+	"github.com/pfmt/serialkey/internal/serialkeytest"
+)
+
+func TestCheckDigit(t *testing.T) {
+	chain := serialkey.NewCheckDigit(serialkey.NewLocal(localOpt), serialkey.Luhn)
+	serialkeytest.Next(t, chain)
+	closer.add(chain.Close)
+}
+
+var checkDigitsTests = []struct {
+	test      string
+	line      string
+	algorithm serialkey.CheckDigits
+	number    string
+	want      string
+}{
+	{
+		test:      "luhn",
+		line:      testline(),
+		algorithm: serialkey.Luhn,
+		number:    "7992739871",
+		want:      "79927398713",
+	}, {
+		test:      "damm",
+		line:      testline(),
+		algorithm: serialkey.Damm,
+		number:    "572",
+		want:      "5724",
+	}, {
+		test:      "mod 97-10",
+		line:      testline(),
+		algorithm: serialkey.Mod97,
+		number:    "794",
+		want:      "79444",
+	},
+}
+
+func TestCheckDigits(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range checkDigitsTests {
+		tt := tt
+
+		t.Run(tt.line+"/"+tt.test, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := tt.algorithm.Append(tt.number)
+			if err != nil {
+				t.Fatalf("append check digits: %s", err)
+			}
+			if got != tt.want {
+				t.Errorf("want: %s, got: %s", tt.want, got)
+			}
+
+			if !tt.algorithm.Validate(got) {
+				t.Errorf("want valid: %s", got)
+			}
+
+			typo := []byte(got)
+			typo[0] = '0' + (typo[0]-'0'+1)%10
+			if tt.algorithm.Validate(string(typo)) {
+				t.Errorf("want invalid: %s", typo)
+			}
+		})
+	}
+}
+
+func TestCheckDigitNext(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	chain := serialkey.NewCheckDigit(serialkey.NewLocal(localOpt), serialkey.Mod97)
+	defer chain.Close()
+
+	for i := 0; i < 100; i++ {
+		value, err := chain.Next(ctx, "customer")
+		if err != nil {
+			t.Fatalf("next: %s", err)
+		}
+
+		if !chain.Validate(strconv.FormatInt(value, 10)) {
+			t.Errorf("want valid: %d", value)
+		}
+	}
+}
+
+func TestCheckDigitLastNeverIssued(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		line  string
+		start int64
+		want  int64
+	}{
+		{
+			name:  "default start",
+			line:  testline(),
+			start: 0,
+			want:  -1,
+		},
+		{
+			name:  "start one",
+			line:  testline(),
+			start: 1,
+			want:  0,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.line+"/"+tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			chain := serialkey.NewCheckDigit(
+				serialkey.NewLocal(serialkey.LocalWithStart(tt.start)),
+				serialkey.Mod97,
+				serialkey.CheckDigitWithStart(tt.start),
+			)
+			defer chain.Close()
+
+			value, err := chain.Last(ctx, "never issued")
+			if err != nil {
+				t.Fatalf("last: %s", err)
+			}
+
+			if value != tt.want {
+				t.Errorf("want the start number minus one: %d, got: %d", tt.want, value)
+			}
+		})
+	}
+}
@@ -3,49 +3,429 @@ package main
 import (
 	"context"
 	_ "embed"
+	"encoding/json"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
+	"os/signal"
+	"strconv"
 	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
 
 	"github.com/alecthomas/kong"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/pfmt/serialkey"
+	"github.com/pfmt/serialkey/resp"
+	"github.com/pfmt/serialkey/server"
 )
 
 func main() {
 	cmd := kong.Parse(&CLI)
 
-	switch cmd.Command() {
-	case "postgresql":
+	ctx := context.Background()
 
-		ctx := context.Background()
+	if cmd.Command() == "serve" {
+		var stop context.CancelFunc
+		ctx, stop = signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
 
-		if strings.TrimSpace(CLI.Postgresql.URL) == "" {
-			fmt.Fprintf(os.Stderr, "missing pgx URL\n")
-			os.Exit(1)
+	} else if CLI.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, CLI.Timeout)
+		defer cancel()
+	}
+
+	if CLI.Actor != "" {
+		ctx = serialkey.WithActor(ctx, CLI.Actor)
+	}
+
+	err := run(ctx, cmd.Command(), os.Stdout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, command string, w io.Writer) error {
+	switch command {
+	case "postgresql", "create-table":
+		return withChain(ctx, func(chain *serialkey.PgxPool) error {
+			err := chain.CreateTable(ctx)
+			if err != nil {
+				return fmt.Errorf("create PostgreSQL table %s: %w", CLI.Table, err)
+			}
+			return nil
+		})
+
+	case "drop-table":
+		if !CLI.DropTable.Yes {
+			return fmt.Errorf("drop PostgreSQL table %s: confirm dropping the table and all of the sequences by the --yes flag", CLI.Table)
+		}
+
+		return withChain(ctx, func(chain *serialkey.PgxPool) error {
+			err := chain.DropTable(ctx)
+			if err != nil {
+				return fmt.Errorf("drop PostgreSQL table %s: %w", CLI.Table, err)
+			}
+			return nil
+		})
+
+	case "next <key>":
+		return withChain(ctx, func(chain *serialkey.PgxPool) error {
+			value, err := chain.Next(ctx, CLI.Next.Key)
+			if err != nil {
+				return err
+			}
+			return printValue(w, CLI.Next.Key, value)
+		})
+
+	case "last <key>":
+		return withChain(ctx, func(chain *serialkey.PgxPool) error {
+			value, err := chain.Last(ctx, CLI.Last.Key)
+			if err != nil {
+				return err
+			}
+			return printValue(w, CLI.Last.Key, value)
+		})
+
+	case "forward <key> <target>":
+		return withChain(ctx, func(chain *serialkey.PgxPool) error {
+			value, err := chain.Forward(ctx, CLI.Forward.Key, CLI.Forward.Target)
+			if err != nil {
+				return err
+			}
+			return printValue(w, CLI.Forward.Key, value)
+		})
+
+	case "reset <key>":
+		return withChain(ctx, func(chain *serialkey.PgxPool) error {
+			return chain.Reset(ctx, CLI.Reset.Key)
+		})
+
+	case "list":
+		return withChain(ctx, func(chain *serialkey.PgxPool) error {
+			records, err := chain.List(ctx)
+			if err != nil {
+				return err
+			}
+			return printRecords(w, records)
+		})
+
+	case "check":
+		return withChain(ctx, func(chain *serialkey.PgxPool) error {
+			err := chain.Health(ctx)
+			if err != nil {
+				return err
+			}
+			return printStatus(w, "ok")
+		})
+
+	case "history <key>":
+		return withChain(ctx, func(chain *serialkey.PgxPool) error {
+			var since time.Time
+			if CLI.History.Since > 0 {
+				since = time.Now().Add(-CLI.History.Since)
+			}
+
+			records, err := chain.History(ctx, CLI.History.Key, since)
+			if err != nil {
+				return err
+			}
+			return printHistory(w, records)
+		})
+
+	case "migrate up":
+		return withChain(ctx, func(chain *serialkey.PgxPool) error {
+			err := chain.Migrate(ctx)
+			if err != nil {
+				return fmt.Errorf("migrate PostgreSQL table %s: %w", CLI.Table, err)
+			}
+			return printStatus(w, "ok")
+		})
+
+	case "migrate status":
+		return withChain(ctx, func(chain *serialkey.PgxPool) error {
+			migrations, err := chain.MigrationStatus(ctx)
+			if err != nil {
+				return err
+			}
+			return printMigrations(w, migrations)
+		})
+
+	case "serve":
+		return serve(ctx)
+
+	default:
+		return fmt.Errorf("unknown command: %s", command)
+	}
+}
+
+func withChain(ctx context.Context, f func(*serialkey.PgxPool) error) error {
+	chain, err := newPgxPool(ctx)
+	if err != nil {
+		return err
+	}
+	defer chain.Close()
+
+	return f(chain)
+}
+
+func newPgxPool(ctx context.Context, opts ...serialkey.PgxPoolOption) (*serialkey.PgxPool, error) {
+	if strings.TrimSpace(CLI.URL) == "" {
+		return nil, fmt.Errorf("missing pgx URL")
+	}
+
+	pool, err := pgxpool.New(ctx, CLI.URL)
+	if err != nil {
+		return nil, fmt.Errorf("pgx connect %s: %w", serialkey.RedactURL(CLI.URL), err)
+	}
+
+	opts = append([]serialkey.PgxPoolOption{
+		serialkey.PgxPoolWithTable(CLI.Table),
+		serialkey.PgxPoolWithStart(CLI.Start),
+		serialkey.PgxPoolWithDialect(dialects[CLI.Dialect]),
+		serialkey.PgxPoolWithHistory(CLI.RecordHistory),
+	}, opts...)
+
+	return serialkey.NewPgxPool(pool, opts...), nil
+}
+
+var dialects = map[string]serialkey.Dialect{
+	serialkey.DialectPostgreSQL.String():  serialkey.DialectPostgreSQL,
+	serialkey.DialectCockroachDB.String(): serialkey.DialectCockroachDB,
+	serialkey.DialectYugabyteDB.String():  serialkey.DialectYugabyteDB,
+}
+
+func newChain(ctx context.Context, backend string, logger *slog.Logger) (serialkey.Chain, error) {
+	switch backend {
+	case "local":
+		return serialkey.NewLocal(serialkey.LocalWithStart(CLI.Start), serialkey.LocalWithLogger(logger)), nil
+	case "pgx":
+		return newPgxPool(ctx, serialkey.PgxPoolWithLogger(logger))
+	default:
+		return nil, fmt.Errorf("unknown backend: %s", backend)
+	}
+}
+
+func serve(ctx context.Context) error {
+	var level slog.Level
+
+	err := level.UnmarshalText([]byte(CLI.Serve.LogLevel))
+	if err != nil {
+		return fmt.Errorf("parse log level: %w", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+
+	chain, err := newChain(ctx, CLI.Serve.Backend, logger)
+	if err != nil {
+		return err
+	}
+
+	chain = serialkey.NewBreaker(
+		chain,
+		serialkey.BreakerWithFailures(CLI.Serve.BreakerFailures),
+		serialkey.BreakerWithCooldown(CLI.Serve.BreakerCooldown),
+		serialkey.BreakerWithMaxInFlight(CLI.Serve.MaxInFlight),
+		serialkey.BreakerWithLogger(logger),
+	)
+	chain = serialkey.NewLogging(chain, logger, serialkey.LoggingWithThreshold(CLI.Serve.SlowThreshold))
+
+	switch CLI.Serve.Protocol {
+	case "resp":
+		err = resp.New(chain, resp.WithTimeout(CLI.Timeout)).ListenAndServe(ctx, CLI.Serve.Addr)
+	default:
+		err = server.New(chain, server.WithTimeout(CLI.Timeout)).ListenAndServe(ctx, CLI.Serve.Addr)
+	}
+	if err != nil {
+		return fmt.Errorf("serve %s: %w", CLI.Serve.Addr, err)
+	}
+
+	return nil
+}
+
+type record struct {
+	Key       string     `json:"key"`
+	Value     int64      `json:"value"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+func printValue(w io.Writer, key string, value int64) error {
+	if CLI.Output == "json" {
+		return json.NewEncoder(w).Encode(record{Key: key, Value: value})
+	}
+
+	_, err := fmt.Fprintln(w, value)
+	return err
+}
+
+func printStatus(w io.Writer, status string) error {
+	if CLI.Output == "json" {
+		return json.NewEncoder(w).Encode(struct {
+			Status string `json:"status"`
+		}{Status: status})
+	}
+
+	_, err := fmt.Fprintln(w, status)
+	return err
+}
+
+func printRecords(w io.Writer, records []serialkey.Record) error {
+	if CLI.Output == "json" {
+		out := make([]record, 0, len(records))
+		for _, r := range records {
+			r := r
+			out = append(out, record{Key: r.Key, Value: r.Value, CreatedAt: &r.CreatedAt, UpdatedAt: r.UpdatedAt})
 		}
+		return json.NewEncoder(w).Encode(out)
+	}
 
-		pool, err := pgxpool.New(ctx, CLI.Postgresql.URL)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "pgx connect %s: %s\n", CLI.Postgresql.URL, err)
-			os.Exit(1)
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "KEY\tVALUE\tCREATED AT\tUPDATED AT")
+
+	for _, r := range records {
+		updated := "-"
+		if r.UpdatedAt != nil {
+			updated = r.UpdatedAt.Format(time.RFC3339)
 		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\n", r.Key, r.Value, r.CreatedAt.Format(time.RFC3339), updated)
+	}
+
+	return tw.Flush()
+}
+
+type historyRecord struct {
+	Key       string    `json:"key"`
+	OldValue  *int64    `json:"old_value"`
+	NewValue  *int64    `json:"new_value"`
+	Operation string    `json:"operation"`
+	Actor     string    `json:"actor,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
 
-		err = serialkey.NewPgxPool(pool).CreateTable(ctx)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "create PostgreSQL table %s: %s\n", CLI.Postgresql.URL, err)
-			os.Exit(1)
+func printHistory(w io.Writer, records []serialkey.HistoryRecord) error {
+	if CLI.Output == "json" {
+		out := make([]historyRecord, 0, len(records))
+		for _, r := range records {
+			out = append(out, historyRecord(r))
 		}
+		return json.NewEncoder(w).Encode(out)
+	}
 
-	default:
-		fmt.Fprint(os.Stderr, cmd.Command())
-		os.Exit(1)
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "KEY\tOLD VALUE\tNEW VALUE\tOPERATION\tACTOR\tCREATED AT")
+
+	value := func(v *int64) string {
+		if v == nil {
+			return "-"
+		}
+		return strconv.FormatInt(*v, 10)
 	}
+
+	for _, r := range records {
+		actor := r.Actor
+		if actor == "" {
+			actor = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			r.Key, value(r.OldValue), value(r.NewValue), r.Operation, actor, r.CreatedAt.Format(time.RFC3339))
+	}
+
+	return tw.Flush()
+}
+
+type migration struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+func printMigrations(w io.Writer, migrations []serialkey.Migration) error {
+	if CLI.Output == "json" {
+		out := make([]migration, 0, len(migrations))
+		for _, m := range migrations {
+			out = append(out, migration{Version: m.Version, Name: m.Name, AppliedAt: m.AppliedAt})
+		}
+		return json.NewEncoder(w).Encode(out)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED AT")
+
+	for _, m := range migrations {
+		applied := "pending"
+		if m.AppliedAt != nil {
+			applied = m.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\n", m.Version, m.Name, applied)
+	}
+
+	return tw.Flush()
 }
 
 var CLI struct {
-	Postgresql struct {
-		URL   string `env:"PGXURL" default:"postgres://postgres@localhost:5432/postgres" help:"Specify a PostgreSQL connection. ${env}=${default}"`
-		Table string `env:"TABLE" default:"serialkeys" help:"Specify an alternate table name. ${env}=${default}"`
-	} `cmd:"" help:"Create PostgreSQL table."`
+	URL           string        `env:"PGXURL" default:"postgres://postgres@localhost:5432/postgres" help:"Specify a PostgreSQL connection. ${env}=${default}"`
+	Table         string        `env:"TABLE" default:"serialkeys" help:"Specify an alternate table name. ${env}=${default}"`
+	Start         int64         `env:"START" default:"1" help:"Specify the start number of new sequences, unlike the library default of 0. ${env}=${default}"`
+	Dialect       string        `env:"DIALECT" enum:"postgresql,cockroachdb,yugabytedb" default:"postgresql" help:"Specify the SQL dialect: postgresql, cockroachdb or yugabytedb. ${env}=${default}"`
+	Output        string        `short:"o" enum:"plain,json" default:"plain" help:"Specify the output format: plain or json."`
+	Timeout       time.Duration `default:"30s" help:"Specify the command timeout or the request timeout of the server."`
+	RecordHistory bool          `name:"history" env:"HISTORY" default:"false" help:"Record the changes of the sequences in the history table. ${env}=${default}"`
+	Actor         string        `env:"ACTOR" help:"Specify the actor recorded in the history of the changes. ${env}"`
+
+	Postgresql struct{} `cmd:"" hidden:"" help:"Create PostgreSQL table, the alias of the create-table command."`
+
+	CreateTable struct{} `cmd:"" help:"Create PostgreSQL table."`
+	DropTable   struct {
+		Yes bool `help:"Confirm dropping the table and all of the sequences."`
+	} `cmd:"" help:"Drop PostgreSQL table."`
+
+	Next struct {
+		Key string `arg:"" help:"Sequence key name."`
+	} `cmd:"" help:"Issue the next value of the sequence."`
+
+	Last struct {
+		Key string `arg:"" help:"Sequence key name."`
+	} `cmd:"" help:"Print the last value of the sequence."`
+
+	Forward struct {
+		Key    string `arg:"" help:"Sequence key name."`
+		Target int64  `arg:"" help:"Target value."`
+	} `cmd:"" help:"Forward the sequence to the target value."`
+
+	Reset struct {
+		Key string `arg:"" help:"Sequence key name."`
+	} `cmd:"" help:"Delete the sequence, so it restarts at the start number."`
+
+	List struct{} `cmd:"" help:"List all of the sequences."`
+
+	Check struct{} `cmd:"" help:"Check the connectivity, the table schema and the write permission."`
+
+	History struct {
+		Key   string        `arg:"" help:"Sequence key name."`
+		Since time.Duration `help:"Specify the age of the oldest change, 0 prints the whole history."`
+	} `cmd:"" help:"Print the history of the changes of the sequence."`
+
+	Migrate struct {
+		Up     struct{} `cmd:"" help:"Apply the pending migrations."`
+		Status struct{} `cmd:"" help:"Print the applied and the pending migrations."`
+	} `cmd:"" help:"Migrate the schema of PostgreSQL table."`
+
+	Serve struct {
+		Addr            string        `env:"ADDR" default:":8080" help:"Specify the HTTP listen address. ${env}=${default}"`
+		Backend         string        `env:"BACKEND" enum:"pgx,local" default:"pgx" help:"Specify the backend: pgx or local. ${env}=${default}"`
+		Protocol        string        `env:"PROTOCOL" enum:"http,resp" default:"http" help:"Specify the protocol: http for HTTP/JSON or resp for Redis. ${env}=${default}"`
+		LogLevel        string        `env:"LOG_LEVEL" enum:"debug,info,warn,error" default:"info" help:"Specify the log level: debug, info, warn or error. ${env}=${default}"`
+		SlowThreshold   time.Duration `env:"SLOW_THRESHOLD" default:"100ms" help:"Specify the duration of the chain call logged as slow. ${env}=${default}"`
+		BreakerFailures int           `env:"BREAKER_FAILURES" default:"5" help:"Specify the number of the consecutive failures opening the circuit breaker, 0 disables the breaker. ${env}=${default}"`
+		BreakerCooldown time.Duration `env:"BREAKER_COOLDOWN" default:"5s" help:"Specify the duration of the open circuit breaker before the probe call. ${env}=${default}"`
+		MaxInFlight     int           `env:"MAX_IN_FLIGHT" default:"0" help:"Specify the maximum number of the concurrent chain calls, 0 does not limit the calls. ${env}=${default}"`
+	} `cmd:"" help:"Serve the sequences over HTTP/JSON or the Redis protocol."`
 }
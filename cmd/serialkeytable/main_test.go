@@ -0,0 +1,286 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/kong"
+	"github.com/jackc/pgx/v5/pgproto3"
+)
+
+var runTests = []struct {
+	name    string
+	line    string
+	args    []string
+	want    string
+	queries []string
+	err     string
+}{
+	{
+		name:    "next",
+		line:    testline(),
+		args:    []string{"next", "foo"},
+		want:    "42\n",
+		queries: []string{"INSERT INTO serialkeys"},
+	},
+	{
+		name:    "next json",
+		line:    testline(),
+		args:    []string{"--output=json", "next", "foo"},
+		want:    `{"key":"foo","value":42}` + "\n",
+		queries: []string{"INSERT INTO serialkeys"},
+	},
+	{
+		name:    "last",
+		line:    testline(),
+		args:    []string{"last", "foo"},
+		want:    "7\n",
+		queries: []string{"SELECT value FROM serialkeys"},
+	},
+	{
+		name:    "forward",
+		line:    testline(),
+		args:    []string{"forward", "foo", "100"},
+		want:    "42\n",
+		queries: []string{"INSERT INTO serialkeys", "'100'"},
+	},
+	{
+		name:    "reset",
+		line:    testline(),
+		args:    []string{"reset", "foo"},
+		queries: []string{"DELETE FROM serialkeys WHERE key = 'foo'"},
+	},
+	{
+		name: "list",
+		line: testline(),
+		args: []string{"list"},
+		want: "KEY  VALUE  CREATED AT            UPDATED AT\n" +
+			"foo  42     2022-01-01T00:00:00Z  -\n",
+		queries: []string{"SELECT key, value"},
+	},
+	{
+		name:    "list json",
+		line:    testline(),
+		args:    []string{"--output=json", "list"},
+		want:    `[{"key":"foo","value":42,"created_at":"2022-01-01T00:00:00Z"}]` + "\n",
+		queries: []string{"SELECT key, value"},
+	},
+	{
+		name: "drop table without confirmation",
+		line: testline(),
+		args: []string{"drop-table"},
+		err:  "confirm dropping the table and all of the sequences by the --yes flag",
+	},
+	{
+		name:    "drop table",
+		line:    testline(),
+		args:    []string{"drop-table", "--yes"},
+		queries: []string{"DROP TABLE IF EXISTS serialkeys;"},
+	},
+	{
+		name: "migrate status",
+		line: testline(),
+		args: []string{"migrate", "status"},
+		want: "VERSION  NAME                  APPLIED AT\n" +
+			"1        create_table          pending\n" +
+			"2        create_lease_table    pending\n" +
+			"3        create_history_table  pending\n",
+		queries: []string{"SELECT to_regclass"},
+	},
+}
+
+// TestRun runs the commands sequentially,
+// because the commands share the parsed flags.
+func TestRun(t *testing.T) {
+	for _, tt := range runTests {
+		tt := tt
+
+		t.Run(tt.line+"/"+tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+			defer cancel()
+
+			var (
+				mu      sync.Mutex
+				queries []string
+			)
+
+			url := newFakeServer(t, func(backend *pgproto3.Backend, query string) {
+				mu.Lock()
+				queries = append(queries, query)
+				mu.Unlock()
+
+				switch {
+				case strings.HasPrefix(query, "INSERT"):
+					sendRow(backend, []string{"value"}, []uint32{20}, []string{"42"})
+				case strings.HasPrefix(query, "SELECT value"):
+					sendRow(backend, []string{"value"}, []uint32{20}, []string{"7"})
+				case strings.HasPrefix(query, "SELECT key, value"):
+					sendRow(backend,
+						[]string{"key", "value", "created_at", "updated_at"},
+						[]uint32{25, 20, 1184, 1184},
+						[]string{"foo", "42", "2022-01-01 00:00:00+00", ""})
+				case strings.HasPrefix(query, "SELECT to_regclass"):
+					sendRow(backend, []string{"exists"}, []uint32{16}, []string{"f"})
+				default:
+					backend.Send(&pgproto3.CommandComplete{CommandTag: []byte(strings.ToUpper(strings.Fields(query + " -")[0]))})
+				}
+			})
+
+			command := parse(t, append([]string{"--url=" + url}, tt.args...)...)
+
+			var buf bytes.Buffer
+
+			err := run(ctx, command, &buf)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("want error: %s, got: %v", tt.err, err)
+				}
+			} else if err != nil {
+				t.Fatalf("run %s: %s", command, err)
+			}
+
+			if buf.String() != tt.want {
+				t.Errorf("\nwant: %q\ngot:  %q", tt.want, buf.String())
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			all := strings.Join(queries, "\n")
+
+			for _, q := range tt.queries {
+				if !strings.Contains(all, q) {
+					t.Errorf("want query: %s, got queries: %q", q, queries)
+				}
+			}
+
+			if tt.err != "" && len(queries) != 0 {
+				t.Errorf("want no queries, got: %q", queries)
+			}
+		})
+	}
+}
+
+// parse resets the flags, parses the arguments and returns the command.
+func parse(t *testing.T, args ...string) string {
+	reflect.ValueOf(&CLI).Elem().SetZero()
+
+	parser, err := kong.New(&CLI, kong.Exit(func(code int) { t.Fatalf("exit %d", code) }))
+	if err != nil {
+		t.Fatalf("new parser: %s", err)
+	}
+
+	ctx, err := parser.Parse(args)
+	if err != nil {
+		t.Fatalf("parse %q: %s", args, err)
+	}
+
+	return ctx.Command()
+}
+
+// newFakeServer starts the fake PostgreSQL server of the simple protocol
+// which responds to the queries by the respond function
+// and returns the connection URL.
+func newFakeServer(t *testing.T, respond func(backend *pgproto3.Backend, query string)) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %s", err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+				_ = serveFake(conn, respond)
+			}()
+		}
+	}()
+
+	return fmt.Sprintf(
+		"postgres://postgres@%s/postgres?sslmode=disable&default_query_exec_mode=simple_protocol",
+		ln.Addr(),
+	)
+}
+
+func serveFake(conn net.Conn, respond func(backend *pgproto3.Backend, query string)) error {
+	backend := pgproto3.NewBackend(conn, conn)
+
+	_, err := backend.ReceiveStartupMessage()
+	if err != nil {
+		return err
+	}
+
+	backend.Send(&pgproto3.AuthenticationOk{})
+	backend.Send(&pgproto3.ParameterStatus{Name: "client_encoding", Value: "UTF8"})
+	backend.Send(&pgproto3.ParameterStatus{Name: "standard_conforming_strings", Value: "on"})
+	backend.Send(&pgproto3.BackendKeyData{ProcessID: 1, SecretKey: 1})
+	backend.Send(&pgproto3.ReadyForQuery{TxStatus: 'I'})
+
+	err = backend.Flush()
+	if err != nil {
+		return err
+	}
+
+	for {
+		msg, err := backend.Receive()
+		if err != nil {
+			return err
+		}
+
+		switch msg := msg.(type) {
+		case *pgproto3.Query:
+			respond(backend, msg.String)
+
+			backend.Send(&pgproto3.ReadyForQuery{TxStatus: 'I'})
+
+			err = backend.Flush()
+			if err != nil {
+				return err
+			}
+
+		case *pgproto3.Terminate:
+			return nil
+		}
+	}
+}
+
+// sendRow sends the single row of the text values of the columns
+// of the type OIDs, the empty value is sent as NULL.
+func sendRow(backend *pgproto3.Backend, names []string, oids []uint32, values []string) {
+	fields := make([]pgproto3.FieldDescription, len(names))
+	row := make([][]byte, len(values))
+
+	for i, name := range names {
+		fields[i] = pgproto3.FieldDescription{Name: []byte(name), DataTypeOID: oids[i], DataTypeSize: -1, TypeModifier: -1}
+		if values[i] != "" {
+			row[i] = []byte(values[i])
+		}
+	}
+
+	backend.Send(&pgproto3.RowDescription{Fields: fields})
+	backend.Send(&pgproto3.DataRow{Values: row})
+	backend.Send(&pgproto3.CommandComplete{CommandTag: []byte("SELECT 1")})
+}
+
+func testline() string {
+	_, file, line, ok := runtime.Caller(1)
+	if ok {
+		return fmt.Sprintf("%s:%d", filepath.Base(file), line)
+	}
+	return "it was not possible to recover file and line number information about function invocations"
+}
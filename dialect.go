@@ -0,0 +1,95 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package serialkey
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Dialect is the SQL dialect of the PostgreSQL compatible database.
+type Dialect int
+
+const (
+	// DialectPostgreSQL is the dialect of the PostgreSQL.
+	DialectPostgreSQL Dialect = iota
+	// DialectCockroachDB is the dialect of the CockroachDB,
+	// the statements aborted by the serialization errors are retried
+	// and the schema changes are not guarded by the advisory lock.
+	DialectCockroachDB
+	// DialectYugabyteDB is the dialect of the YugabyteDB,
+	// the statements aborted by the serialization errors are retried
+	// and the schema changes are not guarded by the advisory lock.
+	DialectYugabyteDB
+)
+
+const (
+	// DialectRetries is the maximum number of the retries
+	// of the statement aborted by the serialization error.
+	DialectRetries = 10
+	// DialectBackoff is the delay before the first retry
+	// of the statement aborted by the serialization error,
+	// the delay doubles on each of the subsequent retries.
+	DialectBackoff = time.Millisecond
+)
+
+func (d Dialect) String() string {
+	switch d {
+	case DialectPostgreSQL:
+		return "postgresql"
+	case DialectCockroachDB:
+		return "cockroachdb"
+	case DialectYugabyteDB:
+		return "yugabytedb"
+	default:
+		return "unknown"
+	}
+}
+
+// distributed reports whether the dialect is of the distributed database.
+func (d Dialect) distributed() bool {
+	return d == DialectCockroachDB || d == DialectYugabyteDB
+}
+
+// retry calls the function and on the distributed databases
+// calls the function once again while the function returns
+// the serialization error, the single statement transaction aborted
+// by the serialization error is rolled back, so the retry never
+// issues the value twice.
+func (d Dialect) retry(ctx context.Context, f func() error) error {
+	if !d.distributed() {
+		return f()
+	}
+
+	backoff := DialectBackoff
+
+	for i := 0; ; i++ {
+		err := f()
+		if i == DialectRetries || !isSerializationFailure(err) {
+			return err
+		}
+
+		t := time.NewTimer(backoff)
+
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		case <-t.C:
+		}
+
+		backoff *= 2
+	}
+}
+
+// isSerializationFailure reports whether the error is the serialization
+// failure returned for the transaction aborted by the concurrent one.
+func isSerializationFailure(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "40001"
+}
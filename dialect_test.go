@@ -0,0 +1,268 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package serialkey_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgproto3"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pfmt/serialkey"
+	"github.com/pfmt/serialkey/internal/serialkeytest"
+)
+
+func TestPgxDialectCockroachDB(t *testing.T) {
+	url, ok := os.LookupEnv("COCKROACHURL")
+	if !ok {
+		t.Log("missing CockroachDB URL")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	pool, err := pgxpool.New(ctx, url)
+	if err != nil {
+		t.Fatalf("pgx connect %s: %s", url, err)
+	}
+
+	chain := serialkey.NewPgxPool(pool, pgxOpt, serialkey.PgxPoolWithDialect(serialkey.DialectCockroachDB))
+	t.Cleanup(func() { _ = chain.Close() })
+
+	err = chain.DropTable(ctx)
+	if err != nil {
+		t.Fatalf("drop table: %s", err)
+	}
+
+	err = chain.CreateTable(ctx)
+	if err != nil {
+		t.Fatalf("create table: %s", err)
+	}
+
+	serialkeytest.Next(t, chain)
+	serialkeytest.Forward(t, chain)
+}
+
+var pgxDialectTests = []struct {
+	name     string
+	line     string
+	dialect  serialkey.Dialect
+	failures int32
+	want     int64
+	queries  int32
+	err      bool
+}{
+	{
+		name:     "postgresql does not retry",
+		line:     testline(),
+		dialect:  serialkey.DialectPostgreSQL,
+		failures: 1,
+		queries:  1,
+		err:      true,
+	},
+	{
+		name:    "cockroachdb without failures",
+		line:    testline(),
+		dialect: serialkey.DialectCockroachDB,
+		want:    42,
+		queries: 1,
+	},
+	{
+		name:     "cockroachdb retries serialization failures",
+		line:     testline(),
+		dialect:  serialkey.DialectCockroachDB,
+		failures: 3,
+		want:     42,
+		queries:  4,
+	},
+	{
+		name:     "yugabytedb retries serialization failures",
+		line:     testline(),
+		dialect:  serialkey.DialectYugabyteDB,
+		failures: 5,
+		want:     42,
+		queries:  6,
+	},
+	{
+		name:     "cockroachdb gives up retrying",
+		line:     testline(),
+		dialect:  serialkey.DialectCockroachDB,
+		failures: serialkey.DialectRetries + 1,
+		queries:  serialkey.DialectRetries + 1,
+		err:      true,
+	},
+}
+
+func TestPgxDialectRetry(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range pgxDialectTests {
+		tt := tt
+
+		t.Run(tt.line+"/"+tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			var queries int32
+
+			url := newFaultServer(t, tt.failures, &queries)
+
+			pool, err := pgxpool.New(ctx, url)
+			if err != nil {
+				t.Fatalf("pgx connect %s: %s", url, err)
+			}
+
+			chain := serialkey.NewPgxPool(pool, pgxOpt, serialkey.PgxPoolWithDialect(tt.dialect))
+			t.Cleanup(func() { _ = chain.Close() })
+
+			value, err := chain.Next(ctx, "foo")
+
+			var pgErr *pgconn.PgError
+			if tt.err && (!errors.As(err, &pgErr) || pgErr.Code != "40001") {
+				t.Errorf("want serialization failure, got: %v", err)
+			} else if !tt.err && err != nil {
+				t.Fatalf("next: %s", err)
+			}
+
+			if value != tt.want {
+				t.Errorf("want next value: %d, got: %d", tt.want, value)
+			}
+
+			if got := atomic.LoadInt32(&queries); got != tt.queries {
+				t.Errorf("want queries: %d, got: %d", tt.queries, got)
+			}
+		})
+	}
+}
+
+// newFaultServer starts the fake PostgreSQL server which responds
+// by the serialization failure to the first failures of the queries
+// and by the value 42 to the rest of them, and returns the connection URL.
+func newFaultServer(t *testing.T, failures int32, queries *int32) string {
+	return newFaultCodeServer(t, "40001", failures, queries)
+}
+
+// newFaultCodeServer starts the fake PostgreSQL server which responds
+// by the error of the SQLSTATE code to the first failures of the queries
+// or closes the connection if the code is empty,
+// responds by the value 42 to the rest of the queries
+// and returns the connection URL.
+func newFaultCodeServer(t *testing.T, code string, failures int32, queries *int32) string {
+	return newFakeServer(t, func(backend *pgproto3.Backend, _ string) bool {
+		if atomic.AddInt32(queries, 1) > failures {
+			sendValue(backend, 42)
+			return true
+		}
+
+		if code == "" {
+			return false
+		}
+
+		backend.Send(&pgproto3.ErrorResponse{
+			Severity: "ERROR",
+			Code:     code,
+			Message:  "injected fault",
+		})
+
+		return true
+	})
+}
+
+// newFakeServer starts the fake PostgreSQL server of the simple protocol
+// which responds to the queries by the respond function
+// and returns the connection URL, the connection is closed
+// if the respond function returns false.
+func newFakeServer(t *testing.T, respond func(backend *pgproto3.Backend, query string) bool) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %s", err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+				_ = serveFake(conn, respond)
+			}()
+		}
+	}()
+
+	return fmt.Sprintf(
+		"postgres://postgres@%s/postgres?sslmode=disable&default_query_exec_mode=simple_protocol",
+		ln.Addr(),
+	)
+}
+
+func serveFake(conn net.Conn, respond func(backend *pgproto3.Backend, query string) bool) error {
+	backend := pgproto3.NewBackend(conn, conn)
+
+	_, err := backend.ReceiveStartupMessage()
+	if err != nil {
+		return err
+	}
+
+	backend.Send(&pgproto3.AuthenticationOk{})
+	backend.Send(&pgproto3.ParameterStatus{Name: "client_encoding", Value: "UTF8"})
+	backend.Send(&pgproto3.ParameterStatus{Name: "standard_conforming_strings", Value: "on"})
+	backend.Send(&pgproto3.BackendKeyData{ProcessID: 1, SecretKey: 1})
+	backend.Send(&pgproto3.ReadyForQuery{TxStatus: 'I'})
+
+	err = backend.Flush()
+	if err != nil {
+		return err
+	}
+
+	for {
+		msg, err := backend.Receive()
+		if err != nil {
+			return err
+		}
+
+		switch msg := msg.(type) {
+		case *pgproto3.Query:
+			if !respond(backend, msg.String) {
+				return nil
+			}
+
+			backend.Send(&pgproto3.ReadyForQuery{TxStatus: 'I'})
+
+			err = backend.Flush()
+			if err != nil {
+				return err
+			}
+
+		case *pgproto3.Terminate:
+			return nil
+		}
+	}
+}
+
+// sendValue sends the single row of the bigint value.
+func sendValue(backend *pgproto3.Backend, value int64) {
+	backend.Send(&pgproto3.RowDescription{Fields: []pgproto3.FieldDescription{{
+		Name:         []byte("value"),
+		DataTypeOID:  20,
+		DataTypeSize: 8,
+		TypeModifier: -1,
+	}}})
+	backend.Send(&pgproto3.DataRow{Values: [][]byte{[]byte(strconv.FormatInt(value, 10))}})
+	backend.Send(&pgproto3.CommandComplete{CommandTag: []byte("INSERT 0 1")})
+}
@@ -0,0 +1,302 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package serialkey
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/bits"
+	"strings"
+)
+
+// EncoderAlphabet is the default alphabet of the encoder.
+const EncoderAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// encoderRounds is the number of the Feistel network rounds.
+const encoderRounds = 6
+
+// ErrInvalidID is returned when the public ID cannot be decoded.
+var ErrInvalidID = errors.New("invalid public id")
+
+// NewEncoder returns the encoder mapping the values of the chain
+// to the short, reversible, non-sequential-looking public IDs.
+func NewEncoder(chain Chain, opts ...EncoderOption) (*Encoder, error) {
+	cfg := EncoderConfiguration{alphabet: EncoderAlphabet, minLength: 1}
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if len(cfg.alphabet) < 2 {
+		return nil, fmt.Errorf("alphabet must contain at least two characters: %q", cfg.alphabet)
+	}
+
+	for i := 0; i < len(cfg.alphabet); i++ {
+		if cfg.alphabet[i] >= 0x80 {
+			return nil, fmt.Errorf("alphabet must contain ASCII characters only: %q", cfg.alphabet)
+		}
+		if strings.IndexByte(cfg.alphabet[i+1:], cfg.alphabet[i]) >= 0 {
+			return nil, fmt.Errorf("alphabet must contain unique characters: %q", cfg.alphabet)
+		}
+	}
+
+	if cfg.minLength < 1 {
+		cfg.minLength = 1
+	}
+
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(cfg.salt))
+	state := h.Sum64()
+
+	alphabet := []byte(cfg.alphabet)
+	for i := len(alphabet) - 1; i > 0; i-- {
+		j := splitmix64(&state) % uint64(i+1)
+		alphabet[i], alphabet[j] = alphabet[j], alphabet[i]
+	}
+
+	enc := &Encoder{
+		chain:     chain,
+		alphabet:  string(alphabet),
+		base:      uint64(len(alphabet)),
+		minLength: cfg.minLength,
+	}
+
+	if _, ok := enc.size(enc.minLength); !ok {
+		return nil, fmt.Errorf("minimum length exceeds the 64-bit integer range: %d", enc.minLength)
+	}
+
+	for i := range enc.keys {
+		enc.keys[i] = splitmix64(&state)
+	}
+
+	for i := range enc.index {
+		enc.index[i] = -1
+	}
+	for i := 0; i < len(alphabet); i++ {
+		enc.index[alphabet[i]] = i
+	}
+
+	return enc, nil
+}
+
+// Encoder maps the values of the chain to the public IDs and back.
+// Each value is permuted within the range of the values
+// of the same encoded length by the keyed Feistel network,
+// so the mapping is a bijection and the public IDs are collision-free.
+type Encoder struct {
+	chain     Chain
+	alphabet  string
+	base      uint64
+	minLength int
+	keys      [encoderRounds]uint64
+	index     [128]int
+}
+
+// Next for the passed key name returns the public ID
+// of the next value of the chain.
+// The next method is thread safe if the chain is thread safe.
+func (enc *Encoder) Next(ctx context.Context, key string) (string, error) {
+	value, err := enc.chain.Next(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	return enc.Encode(value), nil
+}
+
+// Last for the passed key name returns the public ID
+// of the last value of the chain.
+// The last method is thread safe if the chain is thread safe.
+func (enc *Encoder) Last(ctx context.Context, key string) (string, error) {
+	value, err := enc.chain.Last(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	return enc.Encode(value), nil
+}
+
+// Forward for the passed key name forwards the chain to the target value
+// and returns the public ID of the result.
+// The forward method is thread safe if the chain is thread safe.
+func (enc *Encoder) Forward(ctx context.Context, key string, target int64) (string, error) {
+	value, err := enc.chain.Forward(ctx, key, target)
+	if err != nil {
+		return "", err
+	}
+	return enc.Encode(value), nil
+}
+
+// Close closes the chain.
+// The close method is thread safe if the chain is thread safe.
+func (enc *Encoder) Close() error {
+	return enc.chain.Close()
+}
+
+// Encode returns the public ID of the value.
+func (enc *Encoder) Encode(value int64) string {
+	x := uint64(value)
+
+	length := enc.minLength
+	for {
+		if size, _ := enc.size(length); size == 0 || x < size {
+			break
+		}
+		length++
+	}
+
+	size, _ := enc.size(length)
+	x = enc.permute(x, size, enc.encrypt)
+
+	buf := make([]byte, length)
+	for i := length - 1; i >= 0; i-- {
+		buf[i] = enc.alphabet[x%enc.base]
+		x /= enc.base
+	}
+
+	return string(buf)
+}
+
+// Decode returns the value of the public ID.
+func (enc *Encoder) Decode(id string) (int64, error) {
+	length := len(id)
+	if length < enc.minLength {
+		return 0, fmt.Errorf("%w: %q is too short", ErrInvalidID, id)
+	}
+
+	size, ok := enc.size(length)
+	if !ok {
+		return 0, fmt.Errorf("%w: %q is too long", ErrInvalidID, id)
+	}
+
+	var x uint64
+
+	for i := 0; i < length; i++ {
+		c := id[i]
+		if c >= 0x80 || enc.index[c] < 0 {
+			return 0, fmt.Errorf("%w: %q contains unknown character %q", ErrInvalidID, id, c)
+		}
+
+		hi, lo := bits.Mul64(x, enc.base)
+		lo, carry := bits.Add64(lo, uint64(enc.index[c]), 0)
+		if hi != 0 || carry != 0 || (size != 0 && lo >= size) {
+			return 0, fmt.Errorf("%w: %q is out of range", ErrInvalidID, id)
+		}
+		x = lo
+	}
+
+	x = enc.permute(x, size, enc.decrypt)
+
+	if length > enc.minLength {
+		if prev, _ := enc.size(length - 1); x < prev {
+			return 0, fmt.Errorf("%w: %q is not canonical", ErrInvalidID, id)
+		}
+	}
+
+	return int64(x), nil
+}
+
+// size returns the number of the values which may be encoded
+// by the passed number of characters, the zero size with the true flag
+// means the whole range of the 64-bit integers
+// and the false flag means the length exceeds the range.
+func (enc *Encoder) size(length int) (uint64, bool) {
+	size := uint64(1)
+
+	for i := 0; i < length; i++ {
+		if size == 0 {
+			return 0, false
+		}
+
+		hi, lo := bits.Mul64(size, enc.base)
+		if hi != 0 {
+			lo = 0
+		}
+		size = lo
+	}
+
+	return size, true
+}
+
+// permute applies the Feistel network to the value
+// walking the cycle until the result is within the size.
+func (enc *Encoder) permute(x, size uint64, round func(uint64, uint) uint64) uint64 {
+	width := uint(64)
+	if size != 0 {
+		width = uint(bits.Len64(size - 1))
+	}
+	if width < 2 {
+		width = 2
+	}
+	width += width % 2
+
+	for {
+		x = round(x, width)
+		if size == 0 || x < size {
+			return x
+		}
+	}
+}
+
+func (enc *Encoder) encrypt(x uint64, width uint) uint64 {
+	half := width / 2
+	mask := uint64(1)<<half - 1
+	l, r := x>>half, x&mask
+
+	for _, k := range enc.keys {
+		l, r = r, l^(mix64(r^k)&mask)
+	}
+
+	return l<<half | r
+}
+
+func (enc *Encoder) decrypt(x uint64, width uint) uint64 {
+	half := width / 2
+	mask := uint64(1)<<half - 1
+	l, r := x>>half, x&mask
+
+	for i := len(enc.keys) - 1; i >= 0; i-- {
+		l, r = r^(mix64(l^enc.keys[i])&mask), l
+	}
+
+	return l<<half | r
+}
+
+func splitmix64(state *uint64) uint64 {
+	*state += 0x9e3779b97f4a7c15
+	return mix64(*state)
+}
+
+func mix64(z uint64) uint64 {
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+// EncoderOption changes configuration.
+type EncoderOption func(*EncoderConfiguration)
+
+// EncoderConfiguration holds values changeable by options.
+type EncoderConfiguration struct {
+	alphabet  string
+	salt      string
+	minLength int
+}
+
+// EncoderWithAlphabet sets the alphabet of the public IDs.
+func EncoderWithAlphabet(alphabet string) EncoderOption {
+	return func(cfg *EncoderConfiguration) { cfg.alphabet = alphabet }
+}
+
+// EncoderWithSalt sets the salt shuffling the alphabet
+// and keying the permutation.
+func EncoderWithSalt(salt string) EncoderOption {
+	return func(cfg *EncoderConfiguration) { cfg.salt = salt }
+}
+
+// EncoderWithMinLength sets the minimum length of the public IDs.
+func EncoderWithMinLength(length int) EncoderOption {
+	return func(cfg *EncoderConfiguration) { cfg.minLength = length }
+}
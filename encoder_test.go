@@ -0,0 +1,162 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package serialkey_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"testing/quick"
+
+	"github.com/pfmt/serialkey"
+)
+
+var encoderTests = []struct {
+	test string
+	line string
+	opts []serialkey.EncoderOption
+}{
+	{
+		test: "default",
+		line: testline(),
+	}, {
+		test: "salt",
+		line: testline(),
+		opts: []serialkey.EncoderOption{serialkey.EncoderWithSalt("pepper")},
+	}, {
+		test: "binary alphabet",
+		line: testline(),
+		opts: []serialkey.EncoderOption{serialkey.EncoderWithAlphabet("01")},
+	}, {
+		test: "decimal alphabet",
+		line: testline(),
+		opts: []serialkey.EncoderOption{serialkey.EncoderWithAlphabet("0123456789")},
+	}, {
+		test: "minimum length",
+		line: testline(),
+		opts: []serialkey.EncoderOption{serialkey.EncoderWithMinLength(8)},
+	},
+}
+
+func TestEncoderRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range encoderTests {
+		tt := tt
+
+		t.Run(tt.line+"/"+tt.test, func(t *testing.T) {
+			t.Parallel()
+
+			enc, err := serialkey.NewEncoder(serialkey.NewLocal(localOpt), tt.opts...)
+			if err != nil {
+				t.Fatalf("new encoder: %s", err)
+			}
+			defer enc.Close()
+
+			roundTrip := func(value int64) bool {
+				got, err := enc.Decode(enc.Encode(value))
+				return err == nil && got == value
+			}
+
+			err = quick.Check(roundTrip, &quick.Config{MaxCount: 10000})
+			if err != nil {
+				t.Error(err)
+			}
+
+			for _, value := range []int64{math.MinInt64, -1, 0, 1, math.MaxInt64} {
+				if !roundTrip(value) {
+					t.Errorf("want round trip: %d", value)
+				}
+			}
+
+			seen := make(map[string]int64)
+			for value := int64(0); value < 100000; value++ {
+				id := enc.Encode(value)
+				if prev, ok := seen[id]; ok {
+					t.Fatalf("want unique public id %q of %d, got the same for %d", id, value, prev)
+				}
+				seen[id] = value
+			}
+		})
+	}
+}
+
+func TestEncoderNext(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	enc, err := serialkey.NewEncoder(serialkey.NewLocal(localOpt), serialkey.EncoderWithSalt("orders"))
+	if err != nil {
+		t.Fatalf("new encoder: %s", err)
+	}
+	defer enc.Close()
+
+	var sequential int
+
+	prev := ""
+	for i := 0; i < 1000; i++ {
+		id, err := enc.Next(ctx, "order")
+		if err != nil {
+			t.Fatalf("next: %s", err)
+		}
+		if id < prev {
+			sequential--
+		} else {
+			sequential++
+		}
+		prev = id
+	}
+
+	if sequential > 900 || sequential < -900 {
+		t.Errorf("want non-sequential public ids, got %d ordered pairs", sequential)
+	}
+
+	last, err := enc.Last(ctx, "order")
+	if err != nil {
+		t.Fatalf("last: %s", err)
+	}
+	if last != prev {
+		t.Errorf("want last public id: %s, got: %s", prev, last)
+	}
+
+	value, err := enc.Decode(last)
+	if err != nil {
+		t.Fatalf("decode: %s", err)
+	}
+	if value != 1000 {
+		t.Errorf("want decoded value: 1000, got: %d", value)
+	}
+}
+
+func TestEncoderDecodeInvalid(t *testing.T) {
+	t.Parallel()
+
+	enc, err := serialkey.NewEncoder(serialkey.NewLocal(localOpt))
+	if err != nil {
+		t.Fatalf("new encoder: %s", err)
+	}
+	defer enc.Close()
+
+	for _, id := range []string{"", "-", "aaaaaaaaaaaaaaaaaaaaaaaaaaaa"} {
+		_, err := enc.Decode(id)
+		if !errors.Is(err, serialkey.ErrInvalidID) {
+			t.Errorf("want invalid public id error for %q, got: %v", id, err)
+		}
+	}
+}
+
+func TestEncoderInvalidAlphabet(t *testing.T) {
+	t.Parallel()
+
+	for _, alphabet := range []string{"", "a", "aba", "abé"} {
+		_, err := serialkey.NewEncoder(serialkey.NewLocal(), serialkey.EncoderWithAlphabet(alphabet))
+		if err == nil {
+			t.Errorf("want error for alphabet %q", alphabet)
+		}
+	}
+}
@@ -0,0 +1,436 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package serialkey
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+
+	"go.uber.org/multierr"
+)
+
+const (
+	// FailoverInterval is the default interval between the attempts
+	// to return to the primary chain after the failover.
+	FailoverInterval = time.Second
+	// FailoverMaxInstances is the maximum number of the failover instances
+	// sharing the primary chain.
+	FailoverMaxInstances = 1 << 16
+)
+
+// NewFailover returns the serialkeys keychain which issues values
+// from the primary chain and falls back to the secondary chain
+// when the primary chain fails.
+// NewFailover returns the error if the secondary chain is not durable
+// or the instance is out of the range of the instances.
+func NewFailover(primary, secondary Chain, opts ...FailoverOption) (*Failover, error) {
+	cfg := FailoverConfiguration{interval: FailoverInterval, instances: 1}
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if !Durable(secondary) {
+		return nil, fmt.Errorf("secondary chain of the failover must be durable: %T", secondary)
+	}
+
+	if cfg.instances < 1 || cfg.instances > FailoverMaxInstances || cfg.instance < 0 || cfg.instance >= cfg.instances {
+		return nil, fmt.Errorf("failover instance %d out of range [0,%d)", cfg.instance, cfg.instances)
+	}
+
+	return &Failover{
+		primary:   primary,
+		secondary: secondary,
+		interval:  cfg.interval,
+		logger:    cfg.logger,
+		spaces:    cfg.instances + 1,
+		instance:  cfg.instance,
+		now:       time.Now,
+		keys:      make(map[string]*failoverKey),
+	}, nil
+}
+
+// Failover is the serialkeys keychain falling back to the secondary chain.
+//
+// The chains own the disjoint value spaces interleaved by the modulo
+// of the number of the instances plus one: the value v of the primary chain
+// is issued as v*(n+1) and the value v of the secondary chain
+// of the instance i is issued as v*(n+1)+i+1, so neither the chains
+// nor the instances sharing the primary chain issue the same value.
+// By default the single instance issues the even values
+// of the primary chain and the odd values of the secondary chain.
+//
+// Every client of the primary chain must go through the failover chain
+// with the distinct instance, the values of the primary chain used directly
+// are not partitioned and collide with the values of the failover chains.
+// The secondary chain must be durable and owned by the single instance,
+// so the values issued before the restart are never issued again.
+//
+// The interleaving keeps the values of the both chains in the same range,
+// so switching of the chains keeps the values increasing:
+// on the failover the secondary chain is forwarded past the last value
+// issued for the key and on the recovery the primary chain is forwarded
+// past the values issued by the secondary chain.
+// The last values are tracked in the memory of the host, so the values
+// issued for the keys not seen by the failover chain since the start
+// are unique but may be less than the values issued before the start.
+type Failover struct {
+	sync.Mutex
+	primary   Chain
+	secondary Chain
+	interval  time.Duration
+	logger    *slog.Logger
+	spaces    int64
+	instance  int64
+	now       func() time.Time
+	keys      map[string]*failoverKey
+	down      bool
+	retry     time.Time
+}
+
+// failoverKey is the state of the key.
+type failoverKey struct {
+	// last is the last value issued for the key.
+	last int64
+	// secondary is true if the last value issued by the secondary chain.
+	secondary bool
+}
+
+// Next for the passed key name returns an value guaranteed to be greater
+// than the value returned for the same key name passed at the time
+// of previous call of the next method or the forward method.
+// The next method is thread safe if the underlying chains are thread safe.
+func (chain *Failover) Next(ctx context.Context, key string) (int64, error) {
+	return chain.issue(ctx, key, math.MinInt64, func(c Chain, target int64, forward bool) (int64, error) {
+		if forward {
+			return c.Forward(ctx, key, target)
+		}
+		return c.Next(ctx, key)
+	})
+}
+
+// Last for the passed key name returns the value returned for
+// the same key name passed at the time of previous call
+// of the next method or the forward method.
+// The last method is thread safe if the underlying chains are thread safe.
+func (chain *Failover) Last(ctx context.Context, key string) (int64, error) {
+	secondary := chain.degraded()
+
+	c := chain.primary
+	if secondary {
+		c = chain.secondary
+	}
+
+	i, err := c.Last(ctx, key)
+	if err != nil && !secondary && chain.failover(ctx, err) {
+		secondary = true
+
+		i, err = chain.secondary.Last(ctx, key)
+	}
+
+	if err != nil {
+		return 0, fmt.Errorf("failover last: %w", err)
+	}
+
+	value := chain.partition(i, secondary)
+
+	chain.Lock()
+	defer chain.Unlock()
+
+	if k, ok := chain.keys[key]; ok && k.last > value {
+		value = k.last
+	}
+
+	return value, nil
+}
+
+// Forward for the passed key name returns an value guaranteed
+// to be greater or equal to the target value and guaranteed to be greater
+// than the value returned for the same key name passed at the time
+// of previous call of the forward method or the next method.
+// The forward method is thread safe if the underlying chains are thread safe.
+func (chain *Failover) Forward(ctx context.Context, key string, target int64) (int64, error) {
+	return chain.issue(ctx, key, target, func(c Chain, target int64, _ bool) (int64, error) {
+		return c.Forward(ctx, key, target)
+	})
+}
+
+// Reconcile forwards the primary chain past the values issued
+// by the secondary chain for all of the keys, so the values issued
+// by the primary chain through the other failover instances
+// are greater than the values issued by the secondary chain.
+// The reconcile method is thread safe if the underlying chains are thread safe.
+func (chain *Failover) Reconcile(ctx context.Context) error {
+	chain.Lock()
+
+	targets := make(map[string]int64)
+
+	for key, k := range chain.keys {
+		if k.secondary {
+			targets[key] = k.last
+		}
+	}
+
+	chain.Unlock()
+
+	for key, last := range targets {
+		i, err := chain.primary.Forward(ctx, key, chain.raw(last+1, false))
+		if err != nil {
+			return fmt.Errorf("failover reconcile %s: %w", key, err)
+		}
+
+		value, err := chain.checkPartition(i, false)
+		if err != nil {
+			return fmt.Errorf("failover reconcile %s: %w", key, err)
+		}
+
+		chain.observe(key, value, false)
+		chain.recover()
+	}
+
+	return nil
+}
+
+// Degraded reports whether the values are issued by the secondary chain.
+// The degraded method is thread safe.
+func (chain *Failover) Degraded() bool {
+	chain.Lock()
+	defer chain.Unlock()
+	return chain.down
+}
+
+// Health returns nil if the primary or the secondary chain is healthy,
+// so the degraded failover chain is still usable.
+// The health method is thread safe if the underlying chains are thread safe.
+func (chain *Failover) Health(ctx context.Context) error {
+	primary := Health(ctx, chain.primary)
+	if primary == nil {
+		return nil
+	}
+
+	secondary := Health(ctx, chain.secondary)
+	if secondary == nil {
+		return nil
+	}
+
+	return fmt.Errorf("failover: %w", multierr.Append(primary, secondary))
+}
+
+// Close closes the primary and the secondary chains.
+// The close method is thread safe if the underlying chains are thread safe.
+func (chain *Failover) Close() error {
+	return multierr.Append(chain.primary.Close(), chain.secondary.Close())
+}
+
+// issue issues the value by the primary chain or by the secondary chain
+// if the primary chain fails, the value is forwarded to the target
+// and past the last value issued by the other chain for the key.
+func (chain *Failover) issue(
+	ctx context.Context,
+	key string,
+	target int64,
+	f func(c Chain, target int64, forward bool) (int64, error),
+) (int64, error) {
+	if !chain.degraded() {
+		value, err := chain.call(key, target, false, f)
+		if err == nil || !chain.failover(ctx, err) {
+			return value, err
+		}
+	}
+
+	return chain.call(key, target, true, f)
+}
+
+// call issues the value by the primary or the secondary chain.
+func (chain *Failover) call(
+	key string,
+	target int64,
+	secondary bool,
+	f func(c Chain, target int64, forward bool) (int64, error),
+) (int64, error) {
+	c, name := chain.primary, "primary"
+	if secondary {
+		c, name = chain.secondary, "secondary"
+	}
+
+	forward := target != math.MinInt64
+
+	chain.Lock()
+
+	if k, ok := chain.keys[key]; ok && k.secondary != secondary && k.last >= target {
+		target, forward = k.last+1, true
+	}
+
+	chain.Unlock()
+
+	i, err := f(c, chain.raw(target, secondary), forward)
+	if err != nil {
+		return 0, fmt.Errorf("failover %s: %w", name, err)
+	}
+
+	value, err := chain.checkPartition(i, secondary)
+	if err != nil {
+		return 0, fmt.Errorf("failover %s: %w", name, err)
+	}
+
+	chain.observe(key, value, secondary)
+
+	if !secondary {
+		chain.recover()
+	}
+
+	return value, nil
+}
+
+// degraded returns true if the values should be issued
+// by the secondary chain, once per the interval the primary chain
+// is tried to recover.
+func (chain *Failover) degraded() bool {
+	chain.Lock()
+	defer chain.Unlock()
+
+	if !chain.down {
+		return false
+	}
+
+	now := chain.now()
+	if now.Before(chain.retry) {
+		return true
+	}
+
+	chain.retry = now.Add(chain.interval)
+
+	return false
+}
+
+// failover switches to the secondary chain and returns true
+// if the error of the primary chain is not caused by the caller.
+func (chain *Failover) failover(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, ErrInvalidRequest) {
+		return false
+	}
+
+	chain.Lock()
+	defer chain.Unlock()
+
+	if !chain.down && chain.logger != nil {
+		chain.logger.LogAttrs(ctx, slog.LevelWarn, "failover", slog.Any("error", err))
+	}
+
+	chain.down = true
+	chain.retry = chain.now().Add(chain.interval)
+
+	return true
+}
+
+// recover switches back to the primary chain.
+func (chain *Failover) recover() {
+	chain.Lock()
+	defer chain.Unlock()
+
+	if chain.down && chain.logger != nil {
+		chain.logger.LogAttrs(context.Background(), slog.LevelInfo, "recover")
+	}
+
+	chain.down = false
+}
+
+// observe records the last value issued for the key.
+func (chain *Failover) observe(key string, value int64, secondary bool) {
+	chain.Lock()
+	defer chain.Unlock()
+
+	k, ok := chain.keys[key]
+	if !ok {
+		chain.keys[key] = &failoverKey{last: value, secondary: secondary}
+		return
+	}
+
+	if value > k.last {
+		k.last, k.secondary = value, secondary
+	}
+}
+
+// offset returns the offset of the value space of the primary
+// or the secondary chain.
+func (chain *Failover) offset(secondary bool) int64 {
+	if secondary {
+		return chain.instance + 1
+	}
+	return 0
+}
+
+// partition returns the value of the partition of the primary
+// or the secondary chain for the value of the chain.
+func (chain *Failover) partition(i int64, secondary bool) int64 {
+	return i*chain.spaces + chain.offset(secondary)
+}
+
+// checkPartition returns the value of the partition
+// or the error if the value is out of the partition range.
+func (chain *Failover) checkPartition(i int64, secondary bool) (int64, error) {
+	if i > (math.MaxInt64-chain.offset(secondary))/chain.spaces || i < math.MinInt64/chain.spaces {
+		return 0, fmt.Errorf("value %d out of partition range: %w", i, ErrInvalidRequest)
+	}
+	return chain.partition(i, secondary), nil
+}
+
+// raw returns the least value of the primary or the secondary chain
+// which partition value is greater or equal to the target value.
+func (chain *Failover) raw(target int64, secondary bool) int64 {
+	if target == math.MinInt64 {
+		return math.MinInt64
+	}
+
+	offset := chain.offset(secondary)
+	if target < math.MinInt64+offset {
+		return math.MinInt64 / chain.spaces
+	}
+
+	target -= offset
+
+	// Ceiling of the quotient, the division truncates toward zero.
+	i := target / chain.spaces
+	if target%chain.spaces > 0 {
+		i++
+	}
+
+	return i
+}
+
+// FailoverOption changes configuration.
+type FailoverOption func(*FailoverConfiguration)
+
+// FailoverConfiguration holds values changeable by options.
+type FailoverConfiguration struct {
+	interval  time.Duration
+	logger    *slog.Logger
+	instance  int64
+	instances int64
+}
+
+// FailoverWithInterval sets the interval between the attempts
+// to return to the primary chain after the failover.
+func FailoverWithInterval(interval time.Duration) FailoverOption {
+	return func(cfg *FailoverConfiguration) { cfg.interval = interval }
+}
+
+// FailoverWithLogger sets the logger of the failovers and the recoveries.
+func FailoverWithLogger(logger *slog.Logger) FailoverOption {
+	return func(cfg *FailoverConfiguration) { cfg.logger = logger }
+}
+
+// FailoverWithInstance sets the instance of the failover chain
+// and the number of the instances sharing the primary chain,
+// each instance issues the values of the secondary chain
+// from the distinct value space.
+func FailoverWithInstance(instance, instances int64) FailoverOption {
+	return func(cfg *FailoverConfiguration) { cfg.instance, cfg.instances = instance, instances }
+}
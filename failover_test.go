@@ -0,0 +1,438 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package serialkey_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pfmt/serialkey"
+	"github.com/pfmt/serialkey/internal/serialkeytest"
+)
+
+var errDown = errors.New("down")
+
+// downChain is the chain failing while it is down.
+type downChain struct {
+	serialkey.Chain
+	down  atomic.Bool
+	calls atomic.Int32
+}
+
+func (chain *downChain) Next(ctx context.Context, key string) (int64, error) {
+	chain.calls.Add(1)
+	if chain.down.Load() {
+		return 0, errDown
+	}
+	return chain.Chain.Next(ctx, key)
+}
+
+func (chain *downChain) Last(ctx context.Context, key string) (int64, error) {
+	chain.calls.Add(1)
+	if chain.down.Load() {
+		return 0, errDown
+	}
+	return chain.Chain.Last(ctx, key)
+}
+
+func (chain *downChain) Forward(ctx context.Context, key string, target int64) (int64, error) {
+	chain.calls.Add(1)
+	if chain.down.Load() {
+		return 0, errDown
+	}
+	return chain.Chain.Forward(ctx, key, target)
+}
+
+// healthChain is the chain which health check returns the error.
+type healthChain struct {
+	serialkey.Chain
+	err error
+}
+
+func (chain healthChain) Health(context.Context) error {
+	return chain.err
+}
+
+func (chain healthChain) Durable() bool {
+	return serialkey.Durable(chain.Chain)
+}
+
+// newFailover returns the failover chain or fails the test.
+func newFailover(t testing.TB, primary, secondary serialkey.Chain, opts ...serialkey.FailoverOption) *serialkey.Failover {
+	chain, err := serialkey.NewFailover(primary, secondary, opts...)
+	if err != nil {
+		t.Fatalf("new failover: %s", err)
+	}
+	return chain
+}
+
+// newFailoverSecondary returns the durable secondary chain of the failover.
+func newFailoverSecondary(t testing.TB) serialkey.Chain {
+	return newBolt(t, filepath.Join(t.TempDir(), "serialkeys.db"), boltOpt)
+}
+
+func TestFailover(t *testing.T) {
+	chain := newFailover(t, serialkey.NewLocal(localOpt), newFailoverSecondary(t))
+	serialkeytest.Next(t, chain)
+	closer.add(chain.Close)
+}
+
+func TestFailoverSwitch(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	primary := &downChain{Chain: serialkey.NewLocal(localOpt)}
+
+	chain := newFailover(t, primary, newFailoverSecondary(t), serialkey.FailoverWithInterval(0))
+	t.Cleanup(func() { _ = chain.Close() })
+
+	var prev int64
+
+	issued := make(map[int64]struct{})
+
+	// Values 1-10 by the primary chain, 11-30 by the secondary chain
+	// and 31-40 by the recovered primary chain.
+	for i := 1; i <= 40; i++ {
+		primary.down.Store(i > 10 && i <= 30)
+
+		value, err := chain.Next(ctx, "foo")
+		if err != nil {
+			t.Fatalf("next %d: %s", i, err)
+		}
+
+		if value <= prev {
+			t.Errorf("want next %d value greater than: %d, got: %d", i, prev, value)
+		}
+
+		if _, ok := issued[value]; ok {
+			t.Errorf("want next %d value unique, got duplicate: %d", i, value)
+		}
+
+		odd := value%2 == 1
+		if secondary := i > 10 && i <= 30; odd != secondary {
+			t.Errorf("want next %d value of the secondary chain: %t, got: %d", i, secondary, value)
+		}
+
+		if degraded := i > 10 && i <= 30; chain.Degraded() != degraded {
+			t.Errorf("want next %d degraded: %t, got: %t", i, degraded, chain.Degraded())
+		}
+
+		issued[value] = struct{}{}
+		prev = value
+	}
+
+	last, err := chain.Last(ctx, "foo")
+	if err != nil {
+		t.Fatalf("last: %s", err)
+	}
+
+	if last != prev {
+		t.Errorf("want last: %d, got: %d", prev, last)
+	}
+}
+
+func TestFailoverInterval(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	primary := &downChain{Chain: serialkey.NewLocal(localOpt)}
+	primary.down.Store(true)
+
+	chain := newFailover(t, primary, newFailoverSecondary(t), serialkey.FailoverWithInterval(time.Hour))
+	t.Cleanup(func() { _ = chain.Close() })
+
+	for i := 0; i < 10; i++ {
+		_, err := chain.Next(ctx, "foo")
+		if err != nil {
+			t.Fatalf("next: %s", err)
+		}
+	}
+
+	if calls := primary.calls.Load(); calls != 1 {
+		t.Errorf("want primary calls within the interval: 1, got: %d", calls)
+	}
+}
+
+func TestFailoverForward(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	primary := &downChain{Chain: serialkey.NewLocal(localOpt)}
+
+	chain := newFailover(t, primary, newFailoverSecondary(t), serialkey.FailoverWithInterval(0))
+	t.Cleanup(func() { _ = chain.Close() })
+
+	tests := []struct {
+		name   string
+		down   bool
+		target int64
+	}{
+		{name: "primary", target: 100},
+		{name: "secondary", down: true, target: 201},
+		{name: "secondary past target", down: true, target: 300},
+		{name: "recovered primary", target: 400},
+	}
+
+	var prev int64
+
+	for _, tt := range tests {
+		primary.down.Store(tt.down)
+
+		value, err := chain.Forward(ctx, "foo", tt.target)
+		if err != nil {
+			t.Fatalf("%s: forward: %s", tt.name, err)
+		}
+
+		if value < tt.target || value <= prev {
+			t.Errorf("%s: want forward value at least: %d and greater than: %d, got: %d", tt.name, tt.target, prev, value)
+		}
+
+		if odd := value%2 == 1; odd != tt.down {
+			t.Errorf("%s: want value of the secondary chain: %t, got: %d", tt.name, tt.down, value)
+		}
+
+		prev = value
+	}
+}
+
+func TestFailoverReconcile(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	local := serialkey.NewLocal(localOpt)
+	primary := &downChain{Chain: local}
+	primary.down.Store(true)
+
+	chain := newFailover(t, primary, newFailoverSecondary(t), serialkey.FailoverWithInterval(time.Hour))
+	t.Cleanup(func() { _ = chain.Close() })
+
+	var value int64
+
+	for i := 0; i < 5; i++ {
+		var err error
+
+		value, err = chain.Next(ctx, "foo")
+		if err != nil {
+			t.Fatalf("next: %s", err)
+		}
+	}
+
+	primary.down.Store(false)
+
+	err := chain.Reconcile(ctx)
+	if err != nil {
+		t.Fatalf("reconcile: %s", err)
+	}
+
+	if chain.Degraded() {
+		t.Errorf("want recovered chain")
+	}
+
+	last, err := local.Last(ctx, "foo")
+	if err != nil {
+		t.Fatalf("last: %s", err)
+	}
+
+	if 2*last <= value {
+		t.Errorf("want primary forwarded past: %d, got: %d", value, 2*last)
+	}
+}
+
+func TestFailoverInstances(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	primary := &downChain{Chain: serialkey.NewLocal(localOpt)}
+
+	const instances = 3
+
+	var chains []*serialkey.Failover
+
+	for i := int64(0); i < instances; i++ {
+		chain := newFailover(t,
+			primary,
+			newFailoverSecondary(t),
+			serialkey.FailoverWithInterval(0),
+			serialkey.FailoverWithInstance(i, instances),
+		)
+		chains = append(chains, chain)
+	}
+
+	var (
+		mu     sync.Mutex
+		issued = make(map[int64]int)
+		wg     sync.WaitGroup
+	)
+
+	// The values 1-10 by the primary chain, 11-20 by the secondary chains
+	// and 21-30 by the recovered primary chain.
+	for i := 1; i <= 30; i++ {
+		primary.down.Store(i > 10 && i <= 20)
+
+		for n, chain := range chains {
+			wg.Add(1)
+
+			go func(n int, chain *serialkey.Failover) {
+				defer wg.Done()
+
+				value, err := chain.Next(ctx, "foo")
+				if err != nil {
+					t.Errorf("instance %d next: %s", n, err)
+					return
+				}
+
+				mu.Lock()
+				defer mu.Unlock()
+
+				if prev, ok := issued[value]; ok {
+					t.Errorf("instance %d duplicated value %d issued by instance %d", n, value, prev)
+				}
+				issued[value] = n
+			}(n, chain)
+		}
+
+		wg.Wait()
+	}
+}
+
+func TestNewFailover(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		line      string
+		secondary func(testing.TB) serialkey.Chain
+		opts      []serialkey.FailoverOption
+		err       bool
+	}{
+		{
+			name:      "durable secondary",
+			line:      testline(),
+			secondary: newFailoverSecondary,
+		},
+		{
+			name: "wrapped durable secondary",
+			line: testline(),
+			secondary: func(t testing.TB) serialkey.Chain {
+				return serialkey.NewLogging(newFailoverSecondary(t), nil)
+			},
+		},
+		{
+			name:      "local secondary",
+			line:      testline(),
+			secondary: func(testing.TB) serialkey.Chain { return serialkey.NewLocal(localOpt) },
+			err:       true,
+		},
+		{
+			name: "wrapped local secondary",
+			line: testline(),
+			secondary: func(testing.TB) serialkey.Chain {
+				return serialkey.NewLogging(serialkey.NewLocal(localOpt), nil)
+			},
+			err: true,
+		},
+		{
+			name:      "instance out of range",
+			line:      testline(),
+			secondary: newFailoverSecondary,
+			opts:      []serialkey.FailoverOption{serialkey.FailoverWithInstance(2, 2)},
+			err:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.line+"/"+tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			chain, err := serialkey.NewFailover(serialkey.NewLocal(localOpt), tt.secondary(t), tt.opts...)
+			if tt.err {
+				if err == nil {
+					t.Errorf("want new failover error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("new failover: %s", err)
+			}
+			_ = chain.Close()
+		})
+	}
+}
+
+func TestFailoverInvalidRequest(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	chain := newFailover(t, serialkey.NewLocal(localOpt), newFailoverSecondary(t))
+	t.Cleanup(func() { _ = chain.Close() })
+
+	_, err := chain.Forward(ctx, "foo", math.MaxInt64)
+	if !errors.Is(err, serialkey.ErrInvalidRequest) {
+		t.Errorf("want invalid request error, got: %v", err)
+	}
+
+	if chain.Degraded() {
+		t.Errorf("want not degraded chain on the invalid request")
+	}
+}
+
+func BenchmarkFailoverNext(b *testing.B) {
+	chain := newFailover(b, serialkey.NewLocal(localOpt), newFailoverSecondary(b))
+	serialkeytest.BenchmarkNext(b, chain)
+	closer.add(chain.Close)
+}
+
+func TestFailoverHealth(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	tests := []struct {
+		name      string
+		primary   error
+		secondary error
+		err       bool
+	}{
+		{name: "healthy"},
+		{name: "degraded", primary: errDown},
+		{name: "secondary unhealthy", secondary: errDown},
+		{name: "unhealthy", primary: errDown, secondary: errDown, err: true},
+	}
+
+	for _, tt := range tests {
+		chain := newFailover(t,
+			healthChain{Chain: serialkey.NewLocal(), err: tt.primary},
+			healthChain{Chain: newFailoverSecondary(t), err: tt.secondary},
+		)
+
+		err := serialkey.Health(ctx, serialkey.NewBreaker(chain))
+		if tt.err && !errors.Is(err, errDown) {
+			t.Errorf("%s: want health error, got: %v", tt.name, err)
+		} else if !tt.err && err != nil {
+			t.Errorf("%s: health: %s", tt.name, err)
+		}
+	}
+}
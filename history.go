@@ -0,0 +1,94 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package serialkey
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type actorKey struct{}
+
+// WithActor returns the context carrying the actor
+// recorded in the history of the changes of the sequences.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// Actor returns the actor carried by the context
+// or the empty string if the context does not carry the actor.
+func Actor(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// HistoryRecord is the change of the sequence
+// stored in the history table.
+type HistoryRecord struct {
+	Key string
+	// OldValue is nil if the change creates the sequence.
+	OldValue *int64
+	// NewValue is nil if the change deletes the sequence.
+	NewValue *int64
+	// Operation is the operation changed the sequence:
+	// next, next_n, forward or reset.
+	Operation string
+	// Actor is the actor carried by the context of the change
+	// or the empty string.
+	Actor     string
+	CreatedAt time.Time
+}
+
+// History returns the changes of the sequence of the passed key name
+// made since the passed time ordered by the time of the changes.
+// The history method is thread safe.
+func (chain *PgxPool) History(ctx context.Context, key string, since time.Time) ([]HistoryRecord, error) {
+	q, err := PostgreSQL{Table: chain.table}.historyList()
+	if err != nil {
+		return nil, fmt.Errorf("generate the history query: %w", err)
+	}
+
+	var records []HistoryRecord
+
+	err = chain.query(ctx, func(ctx context.Context, conn *pgxpool.Conn) error {
+		records = records[:0]
+
+		rows, err := conn.Query(ctx, q, key, since)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var (
+				r     HistoryRecord
+				actor *string
+			)
+
+			err = rows.Scan(&r.Key, &r.OldValue, &r.NewValue, &r.Operation, &actor, &r.CreatedAt)
+			if err != nil {
+				return fmt.Errorf("scan history: %w", err)
+			}
+
+			if actor != nil {
+				r.Actor = *actor
+			}
+
+			records = append(records, r)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		chain.log(ctx, slog.LevelError, "query failed", slog.String("query", "history"), slog.String("key", key), slog.Any("error", err))
+		return nil, fmt.Errorf("fetch history %s: %w", key, err)
+	}
+
+	return records, nil
+}
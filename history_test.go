@@ -0,0 +1,181 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package serialkey_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgproto3"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pfmt/serialkey"
+)
+
+func TestPgxPoolHistory(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var (
+		mu      sync.Mutex
+		queries []string
+	)
+
+	url := newFakeServer(t, func(backend *pgproto3.Backend, query string) bool {
+		mu.Lock()
+		queries = append(queries, query)
+		mu.Unlock()
+
+		switch {
+		case strings.HasPrefix(query, "SELECT key, old_value"):
+			backend.Send(&pgproto3.RowDescription{Fields: []pgproto3.FieldDescription{
+				{Name: []byte("key"), DataTypeOID: 25, DataTypeSize: -1, TypeModifier: -1},
+				{Name: []byte("old_value"), DataTypeOID: 20, DataTypeSize: 8, TypeModifier: -1},
+				{Name: []byte("new_value"), DataTypeOID: 20, DataTypeSize: 8, TypeModifier: -1},
+				{Name: []byte("operation"), DataTypeOID: 25, DataTypeSize: -1, TypeModifier: -1},
+				{Name: []byte("actor"), DataTypeOID: 25, DataTypeSize: -1, TypeModifier: -1},
+				{Name: []byte("created_at"), DataTypeOID: 1184, DataTypeSize: 8, TypeModifier: -1},
+			}})
+			backend.Send(&pgproto3.DataRow{Values: [][]byte{
+				[]byte("foo"), nil, []byte("1"), []byte("next"), nil, []byte("2022-01-01 00:00:00+00"),
+			}})
+			backend.Send(&pgproto3.DataRow{Values: [][]byte{
+				[]byte("foo"), []byte("1"), []byte("42"), []byte("forward"), []byte("alice"), []byte("2022-01-01 00:00:01+00"),
+			}})
+			backend.Send(&pgproto3.CommandComplete{CommandTag: []byte("SELECT 2")})
+		case strings.HasPrefix(query, "WITH"):
+			sendValue(backend, 42)
+		default:
+			backend.Send(&pgproto3.CommandComplete{CommandTag: []byte("DELETE 1")})
+		}
+
+		return true
+	})
+
+	pool, err := pgxpool.New(ctx, url)
+	if err != nil {
+		t.Fatalf("pgx connect %s: %s", url, err)
+	}
+
+	chain := serialkey.NewPgxPool(pool, pgxOpt, serialkey.PgxPoolWithHistory(true))
+	t.Cleanup(func() { _ = chain.Close() })
+
+	actx := serialkey.WithActor(ctx, "alice")
+
+	_, err = chain.Forward(actx, "foo", 42)
+	if err != nil {
+		t.Fatalf("forward: %s", err)
+	}
+
+	err = chain.Reset(actx, "foo")
+	if err != nil {
+		t.Fatalf("reset: %s", err)
+	}
+
+	records, err := chain.History(ctx, "foo", time.Time{})
+	if err != nil {
+		t.Fatalf("history: %s", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	for _, q := range queries[:2] {
+		if !strings.Contains(q, "serialkeys_history") || !strings.Contains(q, "'alice'") {
+			t.Errorf("want the history written by the same statement with the actor, got query: %s", q)
+		}
+	}
+
+	if !strings.Contains(queries[0], "FOR UPDATE") {
+		t.Errorf("want the old value of the forward locked before the upsert, got query: %s", queries[0])
+	}
+
+	if len(records) != 2 {
+		t.Fatalf("want history records: 2, got: %d", len(records))
+	}
+
+	if r := records[0]; r.OldValue != nil || r.NewValue == nil || *r.NewValue != 1 || r.Operation != "next" || r.Actor != "" {
+		t.Errorf("unexpected history record of the created sequence: %+v", r)
+	}
+
+	if r := records[1]; r.OldValue == nil || *r.OldValue != 1 || r.NewValue == nil || *r.NewValue != 42 || r.Actor != "alice" {
+		t.Errorf("unexpected history record of the forwarded sequence: %+v", r)
+	}
+}
+
+func TestPgxHistory(t *testing.T) {
+	if pgxErr != nil {
+		t.Log(pgxErr)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	chain := serialkey.NewPgxPool(
+		pgxPool,
+		pgxOpt,
+		serialkey.PgxPoolWithTable("serialkeys_history_test"),
+		serialkey.PgxPoolWithHistory(true),
+	)
+
+	err := chain.DropTable(ctx)
+	if err != nil {
+		t.Fatalf("drop table: %s", err)
+	}
+
+	err = chain.CreateTable(ctx)
+	if err != nil {
+		t.Fatalf("create table: %s", err)
+	}
+
+	since := time.Now().Add(-time.Minute)
+	actx := serialkey.WithActor(ctx, "alice")
+
+	_, err = chain.Next(actx, "foo")
+	if err != nil {
+		t.Fatalf("next: %s", err)
+	}
+
+	_, err = chain.Forward(actx, "foo", 100)
+	if err != nil {
+		t.Fatalf("forward: %s", err)
+	}
+
+	err = chain.Reset(actx, "foo")
+	if err != nil {
+		t.Fatalf("reset: %s", err)
+	}
+
+	records, err := chain.History(ctx, "foo", since)
+	if err != nil {
+		t.Fatalf("history: %s", err)
+	}
+
+	var operations []string
+	for _, r := range records {
+		operations = append(operations, r.Operation)
+		if r.Actor != "alice" {
+			t.Errorf("want actor alice, got: %+v", r)
+		}
+	}
+
+	if strings.Join(operations, " ") != "next forward reset" {
+		t.Errorf("want operations: next forward reset, got: %v", operations)
+	}
+
+	if len(records) == 3 && (records[1].OldValue == nil || *records[1].OldValue != 1 || *records[1].NewValue != 100) {
+		t.Errorf("unexpected forward history record: %+v", records[1])
+	}
+
+	err = chain.DropTable(ctx)
+	if err != nil {
+		t.Errorf("drop table: %s", err)
+	}
+}
@@ -0,0 +1,269 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package serialkey
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pfmt/serialkey/internal/serialkeyhttp"
+)
+
+// NewHTTPClient returns the serialkeys keychain
+// which talks to the sequence server by the base URL.
+func NewHTTPClient(url string, opts ...HTTPClientOption) *HTTPClient {
+	cfg := HTTPClientConfiguration{
+		retries: 3,
+		backoff: 50 * time.Millisecond,
+	}
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	client := cfg.client
+	if client == nil {
+		client = &http.Client{Transport: &http.Transport{
+			Proxy:               http.ProxyFromEnvironment,
+			MaxIdleConns:        100,
+			MaxIdleConnsPerHost: 100,
+			IdleConnTimeout:     90 * time.Second,
+		}}
+	}
+
+	return &HTTPClient{
+		url:     strings.TrimSuffix(url, "/"),
+		client:  client,
+		retries: cfg.retries,
+		backoff: cfg.backoff,
+	}
+}
+
+// HTTPClient is the serialkeys keychain based on the sequence server.
+type HTTPClient struct {
+	sync.RWMutex
+	url     string
+	client  *http.Client
+	retries int
+	backoff time.Duration
+	closed  bool
+}
+
+// Next for the passed key name returns an value guaranteed to be greater
+// than the value returned for the same key name passed at the time
+// of previous call of the next method or the forward method.
+// The next method is thread safe.
+func (chain *HTTPClient) Next(ctx context.Context, key string) (int64, error) {
+	return chain.call(ctx, "/next", serialkeyhttp.Request{Key: key}, true)
+}
+
+// NextN for the passed key name reserves the count of values
+// and returns the last one of them.
+// The next N method is thread safe.
+func (chain *HTTPClient) NextN(ctx context.Context, key string, count int64) (int64, error) {
+	return chain.call(ctx, "/next-n", serialkeyhttp.Request{Key: key, Count: count}, true)
+}
+
+// Last for the passed key name returns the value returned for
+// the same key name passed at the time of previous call
+// of the next method or the forward method.
+// The last method is thread safe.
+func (chain *HTTPClient) Last(ctx context.Context, key string) (int64, error) {
+	return chain.call(ctx, "/last", serialkeyhttp.Request{Key: key}, false)
+}
+
+// Forward for the passed key name returns an value guaranteed
+// to be greater or equal to the target value and guaranteed to be greater
+// than the value returned for the same key name passed at the time
+// of previous call of the forward method or the next method.
+// The retried forward is sent with the same idempotency token,
+// so the sequence is forwarded once.
+// The forward method is thread safe.
+func (chain *HTTPClient) Forward(ctx context.Context, key string, target int64) (int64, error) {
+	return chain.call(ctx, "/forward", serialkeyhttp.Request{Key: key, Target: target}, true)
+}
+
+// Health checks the health of the sequence server.
+// The health method is thread safe.
+func (chain *HTTPClient) Health(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, chain.url+"/healthz", nil)
+	if err != nil {
+		return fmt.Errorf("new health request: %w", err)
+	}
+
+	resp, err := chain.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("check health: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	var res serialkeyhttp.ErrorResponse
+
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if json.Unmarshal(data, &res) != nil || res.Error == "" {
+		res.Error = strings.TrimSpace(string(data))
+	}
+
+	return fmt.Errorf("check health: %w: status %d: %s", ErrUnavailable, resp.StatusCode, res.Error)
+}
+
+// Close closes the idle connections.
+// The close method is thread safe.
+func (chain *HTTPClient) Close() error {
+	chain.Lock()
+	defer chain.Unlock()
+
+	if !chain.closed {
+		chain.client.CloseIdleConnections()
+		chain.closed = true
+	}
+
+	return nil
+}
+
+func (chain *HTTPClient) call(ctx context.Context, path string, req serialkeyhttp.Request, idempotent bool) (int64, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return 0, fmt.Errorf("encode request %s: %w", req.Key, err)
+	}
+
+	var token string
+
+	if idempotent {
+		buf := make([]byte, 16)
+
+		_, err = rand.Read(buf)
+		if err != nil {
+			return 0, fmt.Errorf("generate idempotency key: %w", err)
+		}
+
+		token = hex.EncodeToString(buf)
+	}
+
+	backoff := chain.backoff
+
+	for attempt := 0; ; attempt++ {
+		value, retry, err := chain.do(ctx, path, body, token)
+		if err == nil || !retry || attempt >= chain.retries {
+			return value, err
+		}
+
+		timer := time.NewTimer(backoff)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return 0, fmt.Errorf("%s %s: %w", path, req.Key, ctx.Err())
+		case <-timer.C:
+		}
+
+		backoff *= 2
+	}
+}
+
+// do sends the request once and reports whether the failed request may be retried.
+func (chain *HTTPClient) do(ctx context.Context, path string, body []byte, token string) (int64, bool, error) {
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, chain.url+path, bytes.NewReader(body))
+	if err != nil {
+		return 0, false, fmt.Errorf("new request %s: %w", path, err)
+	}
+
+	r.Header.Set("Content-Type", "application/json")
+
+	if token != "" {
+		r.Header.Set(serialkeyhttp.IdempotencyHeader, token)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout := time.Until(deadline)
+		if timeout <= 0 {
+			return 0, false, fmt.Errorf("post %s: %w", path, context.DeadlineExceeded)
+		}
+		r.Header.Set(serialkeyhttp.TimeoutHeader, timeout.String())
+	}
+
+	resp, err := chain.client.Do(r)
+	if err != nil {
+		return 0, ctx.Err() == nil, fmt.Errorf("post %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		var res serialkeyhttp.Response
+
+		err = json.NewDecoder(resp.Body).Decode(&res)
+		if err != nil {
+			return 0, false, fmt.Errorf("decode response %s: %w", path, err)
+		}
+
+		return res.Value, false, nil
+	}
+
+	var res serialkeyhttp.ErrorResponse
+
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if json.Unmarshal(data, &res) != nil || res.Error == "" {
+		res.Error = strings.TrimSpace(string(data))
+	}
+
+	err = fmt.Errorf("%s %s", path, res.Error)
+
+	switch res.Code {
+	case serialkeyhttp.CodeInvalidRequest:
+		return 0, false, fmt.Errorf("%w: %s", ErrInvalidRequest, err)
+	case serialkeyhttp.CodeNotImplemented:
+		return 0, false, fmt.Errorf("%w: %s", ErrNotImplemented, err)
+	case serialkeyhttp.CodeUnavailable:
+		return 0, false, fmt.Errorf("%w: %s", ErrUnavailable, err)
+	case serialkeyhttp.CodeDeadlineExceeded:
+		return 0, false, fmt.Errorf("%w: %s", context.DeadlineExceeded, err)
+	case serialkeyhttp.CodeCanceled:
+		return 0, false, fmt.Errorf("%w: %s", context.Canceled, err)
+	}
+
+	retry := resp.StatusCode >= 500 && resp.StatusCode != http.StatusNotImplemented
+
+	return 0, retry, fmt.Errorf("%w: status %d", err, resp.StatusCode)
+}
+
+// HTTPClientOption changes configuration.
+type HTTPClientOption func(*HTTPClientConfiguration)
+
+// HTTPClientConfiguration holds values changeable by options.
+type HTTPClientConfiguration struct {
+	client  *http.Client
+	retries int
+	backoff time.Duration
+}
+
+// HTTPClientWithClient sets the HTTP client reusing the connections.
+func HTTPClientWithClient(client *http.Client) HTTPClientOption {
+	return func(cfg *HTTPClientConfiguration) { cfg.client = client }
+}
+
+// HTTPClientWithRetries sets the maximum number of the retries
+// of the failed requests.
+func HTTPClientWithRetries(retries int) HTTPClientOption {
+	return func(cfg *HTTPClientConfiguration) { cfg.retries = retries }
+}
+
+// HTTPClientWithBackoff sets the initial delay between the retries,
+// the delay is doubled after each retry.
+func HTTPClientWithBackoff(backoff time.Duration) HTTPClientOption {
+	return func(cfg *HTTPClientConfiguration) { cfg.backoff = backoff }
+}
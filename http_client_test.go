@@ -0,0 +1,148 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package serialkey_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/pfmt/serialkey"
+	"github.com/pfmt/serialkey/internal/serialkeytest"
+	"github.com/pfmt/serialkey/server"
+)
+
+func TestHTTPClient(t *testing.T) {
+	ts := httptest.NewServer(server.New(serialkey.NewLocal(localOpt)))
+	t.Cleanup(ts.Close)
+
+	chain := serialkey.NewHTTPClient(ts.URL, serialkey.HTTPClientWithClient(ts.Client()))
+	serialkeytest.Next(t, chain)
+	closer.add(chain.Close)
+}
+
+func BenchmarkHTTPClientNext(b *testing.B) {
+	ts := httptest.NewServer(server.New(serialkey.NewLocal(localOpt)))
+	defer ts.Close()
+
+	chain := serialkey.NewHTTPClient(ts.URL, serialkey.HTTPClientWithClient(ts.Client()))
+	serialkeytest.BenchmarkNext(b, chain)
+	closer.add(chain.Close)
+}
+
+func TestHTTPClientForwardRetry(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	srv := server.New(serialkey.NewLocal(localOpt))
+
+	var calls int32
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, r)
+
+		if r.URL.Path == "/forward" && atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+
+		for k, v := range rec.Header() {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(rec.Code)
+		_, _ = w.Write(rec.Body.Bytes())
+	}))
+	defer ts.Close()
+
+	chain := serialkey.NewHTTPClient(ts.URL, serialkey.HTTPClientWithClient(ts.Client()))
+	defer chain.Close()
+
+	_, err := chain.Next(ctx, "foo")
+	if err != nil {
+		t.Fatalf("next: %s", err)
+	}
+
+	value, err := chain.Forward(ctx, "foo", 10)
+	if err != nil {
+		t.Fatalf("forward: %s", err)
+	}
+	if value != 10 {
+		t.Errorf("want forwarded value: 10, got: %d", value)
+	}
+
+	value, err = chain.Next(ctx, "foo")
+	if err != nil {
+		t.Fatalf("next: %s", err)
+	}
+	if value != 11 {
+		t.Errorf("want next value after the retried forward: 11, got: %d", value)
+	}
+}
+
+func TestHTTPClientErrors(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	ts := httptest.NewServer(server.New(snowflakeChain(t)))
+	defer ts.Close()
+
+	chain := serialkey.NewHTTPClient(ts.URL, serialkey.HTTPClientWithClient(ts.Client()))
+	defer chain.Close()
+
+	_, err := chain.Next(ctx, "")
+	if !errors.Is(err, serialkey.ErrInvalidRequest) {
+		t.Errorf("want invalid request error, got: %v", err)
+	}
+
+	_, err = chain.NextN(ctx, "foo", 42)
+	if !errors.Is(err, serialkey.ErrNotImplemented) {
+		t.Errorf("want not implemented error, got: %v", err)
+	}
+}
+
+func snowflakeChain(t *testing.T) serialkey.Chain {
+	chain, err := serialkey.NewSnowflake()
+	if err != nil {
+		t.Fatalf("new snowflake: %s", err)
+	}
+	return chain
+}
+
+func TestHTTPClientHealth(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	for _, tt := range []struct {
+		name string
+		err  error
+	}{
+		{name: "healthy"},
+		{name: "unhealthy", err: errDown},
+	} {
+		ts := httptest.NewServer(server.New(healthChain{Chain: serialkey.NewLocal(), err: tt.err}))
+		defer ts.Close()
+
+		chain := serialkey.NewHTTPClient(ts.URL, serialkey.HTTPClientWithClient(ts.Client()))
+		defer chain.Close()
+
+		err := chain.Health(ctx)
+		if tt.err == nil && err != nil {
+			t.Errorf("%s: health: %s", tt.name, err)
+		}
+		if tt.err != nil && !errors.Is(err, serialkey.ErrUnavailable) {
+			t.Errorf("%s: want unavailable error, got: %v", tt.name, err)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package serialkeyhttp defines the HTTP/JSON wire format
+// shared by the sequence server and the HTTP client.
+package serialkeyhttp
+
+const (
+	// TimeoutHeader is the request header carrying the request timeout
+	// in the Go duration format, for example 1.5s.
+	TimeoutHeader = "Request-Timeout"
+	// IdempotencyHeader is the request header carrying the unique token
+	// of the request, the retried request with the same token
+	// returns the result of the first one instead of calling the chain again.
+	IdempotencyHeader = "Idempotency-Key"
+)
+
+// Error codes of the error responses.
+const (
+	CodeInvalidRequest   = "invalid_request"
+	CodeNotImplemented   = "not_implemented"
+	CodeUnavailable      = "unavailable"
+	CodeDeadlineExceeded = "deadline_exceeded"
+	CodeCanceled         = "canceled"
+	CodeInternal         = "internal"
+)
+
+// Request is the body of the requests.
+type Request struct {
+	Key    string `json:"key"`
+	Count  int64  `json:"count,omitempty"`
+	Target int64  `json:"target,omitempty"`
+}
+
+// Response is the body of the successful responses.
+type Response struct {
+	Key   string `json:"key"`
+	Value int64  `json:"value"`
+}
+
+// HealthResponse is the body of the successful health check responses.
+type HealthResponse struct {
+	Status string `json:"status"`
+}
+
+// ErrorResponse is the body of the error responses.
+type ErrorResponse struct {
+	Code  string `json:"code"`
+	Error string `json:"error"`
+}
@@ -0,0 +1,306 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package serialkey
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// LeaseKey is the default key name of the leases.
+	LeaseKey = "lease"
+	// LeaseTTL is the default time to live of the lease.
+	LeaseTTL = 30 * time.Second
+)
+
+// ErrLeaseLost is returned when the lease expired or was taken over.
+var ErrLeaseLost = errors.New("lease lost")
+
+// Acquire leases the worker id in the range [0,max) which is unique
+// among the running instances sharing the pgx pool table,
+// renews the lease periodically and frees it on close or expiry.
+func Acquire(ctx context.Context, pool *PgxPool, max int64, opts ...LeaseOption) (*Lease, error) {
+	cfg := LeaseConfiguration{key: LeaseKey, ttl: LeaseTTL}
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if cfg.heartbeat <= 0 {
+		cfg.heartbeat = cfg.ttl / 3
+	}
+
+	if max <= 0 {
+		return nil, fmt.Errorf("lease %s: maximum worker id must be positive: %d", cfg.key, max)
+	}
+
+	db := PostgreSQL{Table: pool.table}
+
+	acquireQuery, err := db.leaseAcquire()
+	if err != nil {
+		return nil, fmt.Errorf("generate the lease acquiring query: %w", err)
+	}
+
+	renewQuery, err := db.leaseRenew()
+	if err != nil {
+		return nil, fmt.Errorf("generate the lease renewal query: %w", err)
+	}
+
+	releaseQuery, err := db.leaseRelease()
+	if err != nil {
+		return nil, fmt.Errorf("generate the lease releasing query: %w", err)
+	}
+
+	holder, err := leaseHolder()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := pool.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	var worker int64
+
+	// The candidate worker id is chosen from the snapshot
+	// taken before the insertion, so the concurrent instance may lease
+	// the same candidate first, then the insertion returns nothing
+	// and the acquisition is retried with the fresh snapshot
+	// until the worker id is leased or the candidates are exhausted.
+	for {
+		var (
+			leased    *int64
+			available bool
+		)
+
+		err = conn.QueryRow(ctx, acquireQuery, cfg.key, max, holder, cfg.ttl.Milliseconds()).Scan(&leased, &available)
+		if err != nil {
+			return nil, fmt.Errorf("acquire lease %s: %w", cfg.key, err)
+		}
+
+		if leased != nil {
+			worker = *leased
+			break
+		}
+
+		if !available {
+			return nil, fmt.Errorf("lease %s: %w: all of %d are in use", cfg.key, ErrNodeExhausted, max)
+		}
+	}
+
+	lease := &Lease{
+		pool:         pool,
+		key:          cfg.key,
+		holder:       holder,
+		worker:       worker,
+		ttl:          cfg.ttl,
+		heartbeat:    cfg.heartbeat,
+		renewQuery:   renewQuery,
+		releaseQuery: releaseQuery,
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+
+	lease.wg.Add(1)
+	go lease.run()
+
+	return lease, nil
+}
+
+// Lease is the worker id leased from the pgx pool table.
+type Lease struct {
+	sync.Mutex
+	pool         *PgxPool
+	key          string
+	holder       string
+	worker       int64
+	ttl          time.Duration
+	heartbeat    time.Duration
+	renewQuery   string
+	releaseQuery string
+	wg           sync.WaitGroup
+	stop         chan struct{}
+	done         chan struct{}
+	err          error
+	closed       bool
+}
+
+// ID returns the leased worker id.
+func (lease *Lease) ID() int64 {
+	return lease.worker
+}
+
+// Done returns the channel closed when the lease is lost or closed.
+func (lease *Lease) Done() <-chan struct{} {
+	return lease.done
+}
+
+// Err returns the reason the lease is lost wrapping the lease lost error
+// or nil.
+// The err method is thread safe.
+func (lease *Lease) Err() error {
+	lease.Lock()
+	defer lease.Unlock()
+
+	return lease.err
+}
+
+func (lease *Lease) run() {
+	defer lease.wg.Done()
+
+	ticker := time.NewTicker(lease.heartbeat)
+	defer ticker.Stop()
+
+	deadline := time.Now().Add(lease.ttl)
+
+	for {
+		select {
+		case <-lease.stop:
+			return
+		case <-ticker.C:
+		}
+
+		start := time.Now()
+
+		ctx, cancel := context.WithTimeout(context.Background(), lease.heartbeat)
+		err := lease.renew(ctx)
+		cancel()
+
+		if err == nil {
+			deadline = start.Add(lease.ttl)
+			continue
+		}
+
+		if errors.Is(err, ErrLeaseLost) {
+			lease.expire(err)
+			return
+		}
+
+		if time.Now().After(deadline) {
+			lease.expire(fmt.Errorf("%w: expired: %s", ErrLeaseLost, err))
+			return
+		}
+	}
+}
+
+func (lease *Lease) renew(ctx context.Context) error {
+	conn, err := lease.pool.conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	var worker int64
+
+	err = conn.QueryRow(ctx, lease.renewQuery, lease.key, lease.worker, lease.holder, lease.ttl.Milliseconds()).Scan(&worker)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("renew lease %s %d: %w", lease.key, lease.worker, ErrLeaseLost)
+
+	} else if err != nil {
+		return fmt.Errorf("renew lease %s %d: %w", lease.key, lease.worker, err)
+	}
+
+	return nil
+}
+
+func (lease *Lease) expire(err error) {
+	lease.Lock()
+	defer lease.Unlock()
+
+	if lease.err == nil {
+		lease.err = err
+		close(lease.done)
+	}
+}
+
+// Close stops the heartbeat and frees the lease.
+// The close method is thread safe.
+func (lease *Lease) Close() error {
+	lease.Lock()
+
+	if lease.closed {
+		lease.Unlock()
+		return nil
+	}
+
+	lease.closed = true
+	close(lease.stop)
+	lease.Unlock()
+
+	lease.wg.Wait()
+
+	lease.Lock()
+	lost := lease.err != nil
+	lease.Unlock()
+
+	lease.expire(fmt.Errorf("%w: closed", ErrLeaseLost))
+
+	if lost {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), lease.ttl)
+	defer cancel()
+
+	conn, err := lease.pool.conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, lease.releaseQuery, lease.key, lease.worker, lease.holder)
+	if err != nil {
+		return fmt.Errorf("release lease %s %d: %w", lease.key, lease.worker, err)
+	}
+
+	return nil
+}
+
+func leaseHolder() (string, error) {
+	buf := make([]byte, 16)
+
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", fmt.Errorf("generate lease holder: %w", err)
+	}
+
+	return hex.EncodeToString(buf), nil
+}
+
+// LeaseOption changes configuration.
+type LeaseOption func(*LeaseConfiguration)
+
+// LeaseConfiguration holds values changeable by options.
+type LeaseConfiguration struct {
+	key       string
+	ttl       time.Duration
+	heartbeat time.Duration
+}
+
+// LeaseWithKey sets the key name scoping the worker ids.
+func LeaseWithKey(key string) LeaseOption {
+	return func(cfg *LeaseConfiguration) { cfg.key = key }
+}
+
+// LeaseWithTTL sets the time after which the not renewed lease expires.
+func LeaseWithTTL(ttl time.Duration) LeaseOption {
+	return func(cfg *LeaseConfiguration) { cfg.ttl = ttl }
+}
+
+// LeaseWithHeartbeat sets the lease renewal interval,
+// the default is one third of the time to live.
+func LeaseWithHeartbeat(heartbeat time.Duration) LeaseOption {
+	return func(cfg *LeaseConfiguration) { cfg.heartbeat = heartbeat }
+}
@@ -0,0 +1,211 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package serialkey_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgproto3"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pfmt/serialkey"
+)
+
+var pgxPoolAcquireTests = []struct {
+	name    string
+	line    string
+	results [][2]string
+	want    int64
+	err     error
+}{
+	{
+		name:    "leased",
+		line:    testline(),
+		results: [][2]string{{"1", "t"}},
+		want:    1,
+	},
+	{
+		name:    "candidate leased concurrently",
+		line:    testline(),
+		results: [][2]string{{"", "t"}, {"", "t"}, {"3", "t"}},
+		want:    3,
+	},
+	{
+		name:    "exhausted",
+		line:    testline(),
+		results: [][2]string{{"", "t"}, {"", "f"}},
+		err:     serialkey.ErrNodeExhausted,
+	},
+}
+
+func TestPgxPoolAcquire(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range pgxPoolAcquireTests {
+		tt := tt
+
+		t.Run(tt.line+"/"+tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			var (
+				mu       sync.Mutex
+				attempts int
+			)
+
+			url := newFakeServer(t, func(backend *pgproto3.Backend, query string) bool {
+				if !strings.HasPrefix(query, "WITH candidate") {
+					backend.Send(&pgproto3.CommandComplete{CommandTag: []byte("UPDATE 1")})
+					return true
+				}
+
+				mu.Lock()
+				r := tt.results[attempts]
+				attempts++
+				mu.Unlock()
+
+				var worker []byte
+				if r[0] != "" {
+					worker = []byte(r[0])
+				}
+
+				backend.Send(&pgproto3.RowDescription{Fields: []pgproto3.FieldDescription{
+					{Name: []byte("worker"), DataTypeOID: 20, DataTypeSize: 8, TypeModifier: -1},
+					{Name: []byte("available"), DataTypeOID: 16, DataTypeSize: 1, TypeModifier: -1},
+				}})
+				backend.Send(&pgproto3.DataRow{Values: [][]byte{worker, []byte(r[1])}})
+				backend.Send(&pgproto3.CommandComplete{CommandTag: []byte("SELECT 1")})
+
+				return true
+			})
+
+			pool, err := pgxpool.New(ctx, url)
+			if err != nil {
+				t.Fatalf("pgx connect %s: %s", url, err)
+			}
+
+			chain := serialkey.NewPgxPool(pool, pgxOpt)
+			t.Cleanup(func() { _ = chain.Close() })
+
+			lease, err := serialkey.Acquire(ctx, chain, 4)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Errorf("want error: %s, got: %v", tt.err, err)
+				}
+			} else if err != nil {
+				t.Fatalf("acquire: %s", err)
+			} else {
+				defer lease.Close()
+
+				if lease.ID() != tt.want {
+					t.Errorf("want worker id: %d, got: %d", tt.want, lease.ID())
+				}
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if attempts != len(tt.results) {
+				t.Errorf("want acquisition attempts: %d, got: %d", len(tt.results), attempts)
+			}
+		})
+	}
+}
+
+func TestPgxLease(t *testing.T) {
+	if pgxErr != nil {
+		t.Log(pgxErr)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	chain := serialkey.NewPgxPool(pgxPool, pgxOpt)
+
+	err := chain.CreateTable(ctx)
+	if err != nil {
+		t.Fatalf("create table: %s", err)
+	}
+
+	key := "test-" + time.Now().Format(time.RFC3339Nano)
+	opts := []serialkey.LeaseOption{
+		serialkey.LeaseWithKey(key),
+		serialkey.LeaseWithTTL(time.Second),
+		serialkey.LeaseWithHeartbeat(100 * time.Millisecond),
+	}
+
+	first, err := serialkey.Acquire(ctx, chain, 2, opts...)
+	if err != nil {
+		t.Fatalf("acquire first lease: %s", err)
+	}
+	defer first.Close()
+
+	second, err := serialkey.Acquire(ctx, chain, 2, opts...)
+	if err != nil {
+		t.Fatalf("acquire second lease: %s", err)
+	}
+	defer second.Close()
+
+	if first.ID() == second.ID() {
+		t.Errorf("want distinct worker ids, got: %d", first.ID())
+	}
+
+	_, err = serialkey.Acquire(ctx, chain, 2, opts...)
+	if !errors.Is(err, serialkey.ErrNodeExhausted) {
+		t.Errorf("want node ids exhausted error, got: %v", err)
+	}
+
+	// The lease outlives the time to live only by the renewal,
+	// so wait until the lease is renewed past the initial expiry.
+	for {
+		var renewed bool
+
+		err = pgxPool.QueryRow(ctx, `SELECT expires_at > created_at + interval '1 second' FROM serialkeys_leases WHERE key = $1 AND worker = $2`,
+			key, first.ID()).Scan(&renewed)
+		if err != nil {
+			t.Fatalf("fetch lease expiry: %s", err)
+		}
+
+		if renewed {
+			break
+		}
+
+		select {
+		case <-first.Done():
+			t.Fatalf("want renewed lease, got: %v", first.Err())
+		case <-ctx.Done():
+			t.Fatalf("wait for lease renewal: %s", ctx.Err())
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+
+	select {
+	case <-first.Done():
+		t.Errorf("want renewed lease, got: %v", first.Err())
+	default:
+	}
+
+	err = first.Close()
+	if err != nil {
+		t.Fatalf("close lease: %s", err)
+	}
+
+	third, err := serialkey.Acquire(ctx, chain, 2, opts...)
+	if err != nil {
+		t.Fatalf("acquire freed lease: %s", err)
+	}
+	defer third.Close()
+
+	if third.ID() != first.ID() {
+		t.Errorf("want freed worker id: %d, got: %d", first.ID(), third.ID())
+	}
+}
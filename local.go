@@ -6,6 +6,7 @@ package serialkey
 
 import (
 	"context"
+	"log/slog"
 	"sync"
 	"sync/atomic"
 )
@@ -20,23 +21,25 @@ func NewLocal(opts ...LocalOption) *Local {
 	}
 
 	return &Local{
-		start: cfg.start,
-		table: make(map[string]*int64),
+		start:  cfg.start,
+		logger: cfg.logger,
+		table:  make(map[string]*int64),
 	}
 }
 
 // Local is the serialkeys keychain based on the local memory.
 type Local struct {
 	sync.RWMutex
-	start int64
-	table map[string]*int64
+	start  int64
+	logger *slog.Logger
+	table  map[string]*int64
 }
 
 // Next for the passed key name returns an value guaranteed to be greater
 // than the value returned for the same key name passed at the time
 // of previous call of the next method or the forward method.
 // The next method is thread safe.
-func (chain *Local) Next(_ context.Context, key string) (int64, error) {
+func (chain *Local) Next(ctx context.Context, key string) (int64, error) {
 	chain.RLock()
 
 	if value, ok := chain.table[key]; ok {
@@ -55,6 +58,34 @@ func (chain *Local) Next(_ context.Context, key string) (int64, error) {
 
 	i := chain.start
 	chain.table[key] = &i
+	chain.log(ctx, "create sequence", key)
+
+	return i, nil
+}
+
+// NextN for the passed key name reserves the count of values
+// and returns the last one of them.
+// The next N method is thread safe.
+func (chain *Local) NextN(ctx context.Context, key string, count int64) (int64, error) {
+	chain.RLock()
+
+	if value, ok := chain.table[key]; ok {
+		i := atomic.AddInt64((*int64)(value), count)
+		chain.RUnlock()
+		return i, nil
+	}
+
+	chain.RUnlock()
+	chain.Lock()
+	defer chain.Unlock()
+
+	if value, ok := chain.table[key]; ok {
+		return atomic.AddInt64((*int64)(value), count), nil
+	}
+
+	i := chain.start + count - 1
+	chain.table[key] = &i
+	chain.log(ctx, "create sequence", key)
 
 	return i, nil
 }
@@ -79,11 +110,12 @@ func (chain *Local) Last(_ context.Context, key string) (int64, error) {
 // than the value returned for the same key name passed at the time
 // of previous call of the forward method or the next method.
 // Forward method is thread safe.
-func (chain *Local) Forward(_ context.Context, key string, target int64) (int64, error) {
+func (chain *Local) Forward(ctx context.Context, key string, target int64) (int64, error) {
 	chain.RLock()
 
 	if value, ok := chain.table[key]; ok {
-		if i := atomic.LoadInt64((*int64)(value)); i >= target {
+		if atomic.LoadInt64((*int64)(value)) >= target {
+			i := atomic.AddInt64((*int64)(value), 1)
 			chain.RUnlock()
 			return i, nil
 		}
@@ -94,31 +126,50 @@ func (chain *Local) Forward(_ context.Context, key string, target int64) (int64,
 	defer chain.Unlock()
 
 	if value, ok := chain.table[key]; ok {
-		if i := atomic.LoadInt64((*int64)(value)); i >= target {
-			return i, nil
+		if atomic.LoadInt64((*int64)(value)) >= target {
+			return atomic.AddInt64((*int64)(value), 1), nil
 		}
 	}
 
+	if _, ok := chain.table[key]; !ok {
+		chain.log(ctx, "create sequence", key)
+	}
+
 	chain.table[key] = &target
 
 	return target, nil
 }
 
-// Close do nothing.
+// Close do nothing except logging.
 // The close method is thread safe.
-func (*Local) Close() error {
+func (chain *Local) Close() error {
+	if chain.logger != nil {
+		chain.logger.LogAttrs(context.Background(), slog.LevelInfo, "close")
+	}
 	return nil
 }
 
+func (chain *Local) log(ctx context.Context, msg, key string) {
+	if chain.logger != nil {
+		chain.logger.LogAttrs(ctx, slog.LevelDebug, msg, slog.String("key", key))
+	}
+}
+
 // LocalOption changes configuration.
 type LocalOption func(*LocalConfiguration)
 
 // LocalConfiguration holds values changeable by options.
 type LocalConfiguration struct {
-	start int64
+	start  int64
+	logger *slog.Logger
 }
 
 // LocalWithStart sets the start number.
 func LocalWithStart(start int64) LocalOption {
 	return func(cfg *LocalConfiguration) { cfg.start = start }
 }
+
+// LocalWithLogger sets the logger of the sequence creation and the closing.
+func LocalWithLogger(logger *slog.Logger) LocalOption {
+	return func(cfg *LocalConfiguration) { cfg.logger = logger }
+}
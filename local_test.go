@@ -8,18 +8,20 @@ import (
 	"testing"
 
 	"github.com/pfmt/serialkey"
+	"github.com/pfmt/serialkey/internal/serialkeytest"
 )
 
 var localOpt = serialkey.LocalWithStart(1)
 
 func TestLocal(t *testing.T) {
 	chain := serialkey.NewLocal(localOpt)
-	serailKeyTest(t, chain)
+	serialkeytest.Next(t, chain)
+	serialkeytest.Forward(t, chain)
 	closer.add(chain.Close)
 }
 
 func BenchmarkLocalNext(b *testing.B) {
 	chain := serialkey.NewLocal(localOpt)
-	nextSerailKeyBenchmark(b, chain)
+	serialkeytest.BenchmarkNext(b, chain)
 	closer.add(chain.Close)
 }
@@ -0,0 +1,226 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package serialkey
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"regexp"
+	"time"
+)
+
+// NewLogging returns the chain logging the calls of the chain,
+// the default logger of the slog package is used if the logger is nil.
+func NewLogging(chain Chain, logger *slog.Logger, opts ...LoggingOption) *Logging {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	cfg := LoggingConfiguration{
+		callLevel:  slog.LevelDebug,
+		slowLevel:  slog.LevelWarn,
+		errorLevel: slog.LevelError,
+	}
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return &Logging{
+		chain:      chain,
+		logger:     logger,
+		threshold:  cfg.threshold,
+		callLevel:  cfg.callLevel,
+		slowLevel:  cfg.slowLevel,
+		errorLevel: cfg.errorLevel,
+	}
+}
+
+// Logging is the serialkeys keychain logging the calls
+// of the underlying chain, the failed calls are logged at the error level,
+// the calls slower than the threshold are logged at the slow level
+// and the rest of the calls are logged at the call level.
+type Logging struct {
+	chain      Chain
+	logger     *slog.Logger
+	threshold  time.Duration
+	callLevel  slog.Level
+	slowLevel  slog.Level
+	errorLevel slog.Level
+}
+
+// Next for the passed key name returns an value guaranteed to be greater
+// than the value returned for the same key name passed at the time
+// of previous call of the next method or the forward method.
+// The next method is thread safe.
+func (chain *Logging) Next(ctx context.Context, key string) (int64, error) {
+	start := time.Now()
+	value, err := chain.chain.Next(ctx, key)
+	chain.log(ctx, "next", start, err, slog.String("key", key), slog.Int64("value", value))
+	return value, err
+}
+
+// NextN for the passed key name reserves the count of values
+// and returns the last one of them.
+// The next N method is thread safe.
+func (chain *Logging) NextN(ctx context.Context, key string, count int64) (int64, error) {
+	start := time.Now()
+
+	var (
+		value int64
+		err   error
+	)
+
+	if c, ok := chain.chain.(NextNer); ok {
+		value, err = c.NextN(ctx, key, count)
+	} else {
+		err = fmt.Errorf("next n: %w", ErrNotImplemented)
+	}
+
+	chain.log(ctx, "next_n", start, err, slog.String("key", key), slog.Int64("count", count), slog.Int64("value", value))
+
+	return value, err
+}
+
+// Last for the passed key name returns the value returned for
+// the same key name passed at the time of previous call
+// of the next method or the forward method.
+// The last method is thread safe.
+func (chain *Logging) Last(ctx context.Context, key string) (int64, error) {
+	start := time.Now()
+	value, err := chain.chain.Last(ctx, key)
+	chain.log(ctx, "last", start, err, slog.String("key", key), slog.Int64("value", value))
+	return value, err
+}
+
+// Forward for the passed key name returns an value guaranteed
+// to be greater or equal to the target value and guaranteed to be greater
+// than the value returned for the same key name passed at the time
+// of previous call of the forward method or the next method.
+// The forward method is thread safe.
+func (chain *Logging) Forward(ctx context.Context, key string, target int64) (int64, error) {
+	start := time.Now()
+	value, err := chain.chain.Forward(ctx, key, target)
+	chain.log(ctx, "forward", start, err, slog.String("key", key), slog.Int64("target", target), slog.Int64("value", value))
+	return value, err
+}
+
+// Health checks the health of the underlying chain
+// and logs the failed checks at the error level.
+// The health method is thread safe.
+func (chain *Logging) Health(ctx context.Context) error {
+	start := time.Now()
+	err := Health(ctx, chain.chain)
+	if err != nil {
+		chain.log(ctx, "health", start, err)
+	}
+	return err
+}
+
+// BlockStats returns the statistics of the block cache
+// of the underlying chain or the zero statistics
+// if the underlying chain does not cache the blocks.
+// The block stats method is thread safe.
+func (chain *Logging) BlockStats() BlockStats {
+	if c, ok := chain.chain.(BlockStater); ok {
+		return c.BlockStats()
+	}
+	return BlockStats{}
+}
+
+// Durable reports whether the underlying chain is durable.
+// The durable method is thread safe.
+func (chain *Logging) Durable() bool {
+	return Durable(chain.chain)
+}
+
+// Close closes the underlying chain.
+// The close method is thread safe.
+func (chain *Logging) Close() error {
+	start := time.Now()
+	err := chain.chain.Close()
+	chain.log(context.Background(), "close", start, err)
+	return err
+}
+
+func (chain *Logging) log(ctx context.Context, method string, start time.Time, err error, attrs ...slog.Attr) {
+	duration := time.Since(start)
+
+	level, msg := chain.callLevel, "chain call"
+
+	if err != nil {
+		level, msg = chain.errorLevel, "chain call failed"
+		attrs = append(attrs, slog.Any("error", err))
+	} else if chain.threshold > 0 && duration >= chain.threshold {
+		level, msg = chain.slowLevel, "slow chain call"
+	}
+
+	if !chain.logger.Enabled(ctx, level) {
+		return
+	}
+
+	attrs = append(attrs, slog.String("method", method), slog.Duration("duration", duration))
+
+	chain.logger.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// passwordRe matches the password of the key/value connection string.
+var passwordRe = regexp.MustCompile(`(?i)(password\s*=\s*)('(?:[^'\\]|\\.)*'|\S+)`)
+
+// RedactURL returns the connection URL or the key/value connection string
+// with the password replaced by the "xxxxx",
+// so the connection may be logged without the secret.
+func RedactURL(conn string) string {
+	if u, err := url.Parse(conn); err == nil && u.Scheme != "" {
+		q := u.Query()
+
+		for k := range q {
+			if k == "password" || k == "sslpassword" {
+				q.Set(k, "xxxxx")
+			}
+		}
+
+		u.RawQuery = q.Encode()
+
+		return u.Redacted()
+	}
+
+	return passwordRe.ReplaceAllString(conn, "${1}xxxxx")
+}
+
+// LoggingOption changes configuration.
+type LoggingOption func(*LoggingConfiguration)
+
+// LoggingConfiguration holds values changeable by options.
+type LoggingConfiguration struct {
+	threshold  time.Duration
+	callLevel  slog.Level
+	slowLevel  slog.Level
+	errorLevel slog.Level
+}
+
+// LoggingWithThreshold sets the duration of the call
+// to be logged as the slow call, the zero threshold disables
+// the logging of the slow calls.
+func LoggingWithThreshold(threshold time.Duration) LoggingOption {
+	return func(cfg *LoggingConfiguration) { cfg.threshold = threshold }
+}
+
+// LoggingWithCallLevel sets the level of the logs of the calls.
+func LoggingWithCallLevel(level slog.Level) LoggingOption {
+	return func(cfg *LoggingConfiguration) { cfg.callLevel = level }
+}
+
+// LoggingWithSlowLevel sets the level of the logs of the slow calls.
+func LoggingWithSlowLevel(level slog.Level) LoggingOption {
+	return func(cfg *LoggingConfiguration) { cfg.slowLevel = level }
+}
+
+// LoggingWithErrorLevel sets the level of the logs of the failed calls.
+func LoggingWithErrorLevel(level slog.Level) LoggingOption {
+	return func(cfg *LoggingConfiguration) { cfg.errorLevel = level }
+}
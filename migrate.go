@@ -0,0 +1,303 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package serialkey
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"log/slog"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Migration is the schema change of the PostgreSQL table.
+type Migration struct {
+	// Version is the ordinal number of the migration starting at 1.
+	Version int64
+	// Name is the name of the migration.
+	Name string
+	// AppliedAt is the time the migration was applied
+	// or nil if the migration is pending.
+	AppliedAt *time.Time
+
+	query string
+}
+
+// Migrations returns the migrations of the PostgreSQL table
+// ordered by the version, the migrations are the embedded SQL files
+// named by the version and the name, for example 0001_create_table.sql.
+func Migrations() ([]Migration, error) {
+	names, err := fs.Glob(postgreSQLMigrations, "psql_migrations/*.sql")
+	if err != nil {
+		return nil, fmt.Errorf("list migrations: %w", err)
+	}
+
+	migrations := make([]Migration, 0, len(names))
+
+	for _, name := range names {
+		base := strings.TrimSuffix(path.Base(name), ".sql")
+
+		v, n, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("migration %s: missing version", name)
+		}
+
+		version, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: invalid version: %s", name, v)
+		}
+
+		data, err := postgreSQLMigrations.ReadFile(name)
+		if err != nil {
+			return nil, fmt.Errorf("read migration %s: %w", name, err)
+		}
+
+		migrations = append(migrations, Migration{Version: version, Name: n, query: string(data)})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	for i, m := range migrations {
+		if m.Version != int64(i+1) {
+			return nil, fmt.Errorf("migration %d %s: want version %d", m.Version, m.Name, i+1)
+		}
+	}
+
+	return migrations, nil
+}
+
+// Migrate applies the pending migrations of the PostgreSQL table
+// in the single transaction, the transaction holds the advisory lock
+// keyed by the table name, so the concurrent instances
+// apply the migrations once.
+// The migrate method is thread safe.
+func (chain *PgxPool) Migrate(ctx context.Context) error {
+	migrations, err := Migrations()
+	if err != nil {
+		return err
+	}
+
+	db := PostgreSQL{Table: chain.table}
+
+	insert, err := db.schemaVersionInsert()
+	if err != nil {
+		return fmt.Errorf("generate the schema version insertion query: %w", err)
+	}
+
+	var applied []Migration
+
+	err = chain.migration(ctx, migrations, func(ctx context.Context, tx pgx.Tx, versions map[int64]Migration) error {
+		applied = applied[:0]
+
+		for _, m := range migrations {
+			if _, ok := versions[m.Version]; ok {
+				continue
+			}
+
+			q, err := db.generate(m.query)
+			if err != nil {
+				return fmt.Errorf("generate the migration %d %s query: %w", m.Version, m.Name, err)
+			}
+
+			_, err = tx.Exec(ctx, q)
+			if err != nil {
+				return fmt.Errorf("apply migration %d %s: %w", m.Version, m.Name, err)
+			}
+
+			_, err = tx.Exec(ctx, insert, m.Version, m.Name)
+			if err != nil {
+				return fmt.Errorf("record migration %d %s: %w", m.Version, m.Name, err)
+			}
+
+			applied = append(applied, m)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+
+	for _, m := range applied {
+		chain.log(ctx, slog.LevelInfo, "apply migration", slog.Int64("version", m.Version), slog.String("name", m.Name))
+	}
+
+	return nil
+}
+
+// MigrationStatus returns the applied and the pending migrations
+// of the PostgreSQL table ordered by the version.
+// The migration status method only reads the applied versions,
+// it neither creates the schema version table nor takes the lock,
+// all of the migrations are pending if the table does not exist.
+// The migration status method is thread safe.
+func (chain *PgxPool) MigrationStatus(ctx context.Context) ([]Migration, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	db := PostgreSQL{Table: chain.table}
+
+	exists, err := db.schemaVersionExists()
+	if err != nil {
+		return nil, fmt.Errorf("generate the schema version table existence query: %w", err)
+	}
+
+	list, err := db.schemaVersionList()
+	if err != nil {
+		return nil, fmt.Errorf("generate the schema version list query: %w", err)
+	}
+
+	err = chain.query(ctx, func(ctx context.Context, conn *pgxpool.Conn) error {
+		var ok bool
+
+		err := conn.QueryRow(ctx, exists).Scan(&ok)
+		if err != nil {
+			return fmt.Errorf("check schema version table: %w", err)
+		}
+
+		if !ok {
+			return nil
+		}
+
+		rows, err := conn.Query(ctx, list)
+		if err != nil {
+			return fmt.Errorf("list schema versions: %w", err)
+		}
+
+		versions, err := chain.versions(rows, int64(len(migrations)))
+		if err != nil {
+			return err
+		}
+
+		for i, m := range migrations {
+			if v, ok := versions[m.Version]; ok {
+				migrations[i].AppliedAt = v.AppliedAt
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("migration status: %w", err)
+	}
+
+	return migrations, nil
+}
+
+// migration calls the function within the transaction holding
+// the advisory lock of the table and passes the applied migrations
+// to the function, the transaction is committed if the function succeeds.
+func (chain *PgxPool) migration(ctx context.Context, migrations []Migration, f func(context.Context, pgx.Tx, map[int64]Migration) error) error {
+	db := PostgreSQL{Table: chain.table}
+
+	create, err := db.schemaVersionCreate()
+	if err != nil {
+		return fmt.Errorf("generate the schema version table creation query: %w", err)
+	}
+
+	list, err := db.schemaVersionList()
+	if err != nil {
+		return fmt.Errorf("generate the schema version list query: %w", err)
+	}
+
+	return chain.query(ctx, func(ctx context.Context, conn *pgxpool.Conn) error {
+		return chain.locked(ctx, conn, func(tx pgx.Tx) error {
+			_, err := tx.Exec(ctx, create)
+			if err != nil {
+				return fmt.Errorf("create schema version table: %w", err)
+			}
+
+			rows, err := tx.Query(ctx, list)
+			if err != nil {
+				return fmt.Errorf("list schema versions: %w", err)
+			}
+
+			versions, err := chain.versions(rows, int64(len(migrations)))
+			if err != nil {
+				return err
+			}
+
+			return f(ctx, tx, versions)
+		})
+	})
+}
+
+// versions scans and closes the rows of the applied migrations.
+// The table applied a migration newer than the latest known one
+// is reported as the schema mismatch.
+func (chain *PgxPool) versions(rows pgx.Rows, latest int64) (map[int64]Migration, error) {
+	defer rows.Close()
+
+	versions := make(map[int64]Migration)
+
+	for rows.Next() {
+		var (
+			m         Migration
+			appliedAt time.Time
+		)
+
+		err := rows.Scan(&m.Version, &m.Name, &appliedAt)
+		if err != nil {
+			return nil, fmt.Errorf("scan schema version: %w", err)
+		}
+
+		m.AppliedAt = &appliedAt
+		versions[m.Version] = m
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list schema versions: %w", err)
+	}
+
+	for v, m := range versions {
+		if v > latest {
+			return nil, fmt.Errorf("table %s schema version %d %s is newer than the latest known %d: %w",
+				chain.table, v, m.Name, latest, ErrSchemaMismatch)
+		}
+	}
+
+	return versions, nil
+}
+
+// locked calls the function within the transaction holding
+// the advisory lock of the table, the transaction is committed
+// if the function succeeds and rolled back otherwise.
+// The distributed dialects serialize the DDL transactions themselves
+// and the advisory locks are not taken.
+func (chain *PgxPool) locked(ctx context.Context, conn *pgxpool.Conn, f func(pgx.Tx) error) error {
+	lock, err := PostgreSQL{Table: chain.table}.advisoryLock()
+	if err != nil {
+		return fmt.Errorf("generate the advisory lock query: %w", err)
+	}
+
+	return pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+		if chain.dialect.distributed() {
+			return f(tx)
+		}
+
+		_, err := tx.Exec(ctx, lock, advisoryLockKey(chain.table))
+		if err != nil {
+			return fmt.Errorf("advisory lock: %w", err)
+		}
+		return f(tx)
+	})
+}
+
+// advisoryLockKey returns the key of the advisory lock of the table.
+func advisoryLockKey(table string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte("serialkey:" + table))
+	return int64(h.Sum64())
+}
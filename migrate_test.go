@@ -0,0 +1,330 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package serialkey_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgproto3"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pfmt/serialkey"
+)
+
+func TestMigrations(t *testing.T) {
+	migrations, err := serialkey.Migrations()
+	if err != nil {
+		t.Fatalf("migrations: %s", err)
+	}
+
+	if len(migrations) == 0 {
+		t.Fatal("want migrations")
+	}
+
+	for i, m := range migrations {
+		if m.Version != int64(i+1) || m.Name == "" || m.AppliedAt != nil {
+			t.Errorf("unexpected migration %d: %+v", i, m)
+		}
+	}
+}
+
+var pgxPoolMigrateTests = []struct {
+	name    string
+	line    string
+	applied []int64
+	want    []int64
+	err     error
+}{
+	{
+		name: "empty table",
+		line: testline(),
+		want: []int64{1, 2, 3},
+	},
+	{
+		name:    "partially migrated table",
+		line:    testline(),
+		applied: []int64{1},
+		want:    []int64{2, 3},
+	},
+	{
+		name:    "migrated table",
+		line:    testline(),
+		applied: []int64{1, 2, 3},
+	},
+	{
+		name:    "table migrated by the newer version",
+		line:    testline(),
+		applied: []int64{1, 2, 3, 1000},
+		err:     serialkey.ErrSchemaMismatch,
+	},
+}
+
+func TestPgxPoolMigrate(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range pgxPoolMigrateTests {
+		tt := tt
+
+		t.Run(tt.line+"/"+tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			var (
+				mu      sync.Mutex
+				queries []string
+				applied []int64
+			)
+
+			url := newFakeServer(t, func(backend *pgproto3.Backend, query string) bool {
+				mu.Lock()
+				defer mu.Unlock()
+
+				queries = append(queries, strings.ToLower(strings.Fields(query + " -")[0]))
+
+				switch {
+				case strings.HasPrefix(query, "SELECT version"):
+					sendVersions(backend, tt.applied)
+				case strings.HasPrefix(query, "INSERT") && strings.Contains(query, "_schema_version"):
+					_, values, _ := strings.Cut(query, "VALUES (")
+					version, _, _ := strings.Cut(values, "::")
+					v, _ := strconv.ParseInt(strings.Trim(version, " '"), 10, 64)
+					applied = append(applied, v)
+					backend.Send(&pgproto3.CommandComplete{CommandTag: []byte("INSERT 0 1")})
+				default:
+					backend.Send(&pgproto3.CommandComplete{CommandTag: []byte(strings.ToUpper(strings.Fields(query + " -")[0]))})
+				}
+
+				return true
+			})
+
+			pool, err := pgxpool.New(ctx, url)
+			if err != nil {
+				t.Fatalf("pgx connect %s: %s", url, err)
+			}
+
+			chain := serialkey.NewPgxPool(pool, pgxOpt)
+			t.Cleanup(func() { _ = chain.Close() })
+
+			err = chain.Migrate(ctx)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Errorf("want error: %s, got: %v", tt.err, err)
+				}
+			} else if err != nil {
+				t.Fatalf("migrate: %s", err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if len(queries) < 2 || queries[0] != "begin" || queries[1] != "select" {
+				t.Errorf("want the advisory lock at the beginning of the transaction, got queries: %v", queries)
+			}
+
+			want := "commit"
+			if tt.err != nil {
+				want = "rollback"
+			}
+
+			if queries[len(queries)-1] != want {
+				t.Errorf("want the transaction finished by %s, got queries: %v", want, queries)
+			}
+
+			if fmt.Sprint(applied) != fmt.Sprint(tt.want) {
+				t.Errorf("want applied migrations: %v, got: %v", tt.want, applied)
+			}
+		})
+	}
+}
+
+var pgxPoolMigrationStatusTests = []struct {
+	name    string
+	line    string
+	exists  bool
+	applied []int64
+	want    []bool
+	err     error
+}{
+	{
+		name: "missing schema version table",
+		line: testline(),
+		want: []bool{false, false, false},
+	},
+	{
+		name:    "partially migrated table",
+		line:    testline(),
+		exists:  true,
+		applied: []int64{1, 2},
+		want:    []bool{true, true, false},
+	},
+	{
+		name:    "table migrated by the newer version",
+		line:    testline(),
+		exists:  true,
+		applied: []int64{1, 2, 3, 1000},
+		err:     serialkey.ErrSchemaMismatch,
+	},
+}
+
+func TestPgxPoolMigrationStatus(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range pgxPoolMigrationStatusTests {
+		tt := tt
+
+		t.Run(tt.line+"/"+tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			var (
+				mu      sync.Mutex
+				queries []string
+			)
+
+			url := newFakeServer(t, func(backend *pgproto3.Backend, query string) bool {
+				mu.Lock()
+				queries = append(queries, query)
+				mu.Unlock()
+
+				switch {
+				case strings.HasPrefix(query, "SELECT to_regclass"):
+					exists := []byte("f")
+					if tt.exists {
+						exists = []byte("t")
+					}
+					backend.Send(&pgproto3.RowDescription{Fields: []pgproto3.FieldDescription{
+						{Name: []byte("exists"), DataTypeOID: 16, DataTypeSize: 1, TypeModifier: -1},
+					}})
+					backend.Send(&pgproto3.DataRow{Values: [][]byte{exists}})
+					backend.Send(&pgproto3.CommandComplete{CommandTag: []byte("SELECT 1")})
+				case strings.HasPrefix(query, "SELECT version"):
+					sendVersions(backend, tt.applied)
+				default:
+					return false
+				}
+
+				return true
+			})
+
+			pool, err := pgxpool.New(ctx, url)
+			if err != nil {
+				t.Fatalf("pgx connect %s: %s", url, err)
+			}
+
+			chain := serialkey.NewPgxPool(pool, pgxOpt)
+			t.Cleanup(func() { _ = chain.Close() })
+
+			migrations, err := chain.MigrationStatus(ctx)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Errorf("want error: %s, got: %v", tt.err, err)
+				}
+			} else if err != nil {
+				t.Fatalf("migration status: %s", err)
+			}
+
+			var applied []bool
+			for _, m := range migrations {
+				applied = append(applied, m.AppliedAt != nil)
+			}
+
+			if fmt.Sprint(applied) != fmt.Sprint(tt.want) {
+				t.Errorf("want applied migrations: %v, got: %v", tt.want, applied)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			for _, q := range queries {
+				if !strings.HasPrefix(q, "SELECT") {
+					t.Errorf("want the read only queries, got query: %s", q)
+				}
+			}
+		})
+	}
+}
+
+func TestPgxMigrate(t *testing.T) {
+	if pgxErr != nil {
+		t.Log(pgxErr)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	chain := serialkey.NewPgxPool(pgxPool, pgxOpt, serialkey.PgxPoolWithTable("serialkeys_migrate_test"))
+
+	err := chain.DropTable(ctx)
+	if err != nil {
+		t.Fatalf("drop table: %s", err)
+	}
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			err := chain.Migrate(ctx)
+			if err != nil {
+				t.Errorf("concurrent migrate: %s", err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	migrations, err := chain.MigrationStatus(ctx)
+	if err != nil {
+		t.Fatalf("migration status: %s", err)
+	}
+
+	for _, m := range migrations {
+		if m.AppliedAt == nil {
+			t.Errorf("want applied migration %d %s", m.Version, m.Name)
+		}
+	}
+
+	err = chain.Health(ctx)
+	if err != nil {
+		t.Errorf("health of the migrated table: %s", err)
+	}
+
+	err = chain.DropTable(ctx)
+	if err != nil {
+		t.Errorf("drop table: %s", err)
+	}
+}
+
+// sendVersions sends the rows of the applied schema versions.
+func sendVersions(backend *pgproto3.Backend, versions []int64) {
+	backend.Send(&pgproto3.RowDescription{Fields: []pgproto3.FieldDescription{
+		{Name: []byte("version"), DataTypeOID: 20, DataTypeSize: 8, TypeModifier: -1},
+		{Name: []byte("name"), DataTypeOID: 25, DataTypeSize: -1, TypeModifier: -1},
+		{Name: []byte("applied_at"), DataTypeOID: 1184, DataTypeSize: 8, TypeModifier: -1},
+	}})
+
+	for _, v := range versions {
+		backend.Send(&pgproto3.DataRow{Values: [][]byte{
+			[]byte(strconv.FormatInt(v, 10)),
+			[]byte(fmt.Sprintf("migration_%d", v)),
+			[]byte("2022-01-01 00:00:00+00"),
+		}})
+	}
+
+	backend.Send(&pgproto3.CommandComplete{CommandTag: []byte(fmt.Sprintf("SELECT %d", len(versions)))})
+}
@@ -0,0 +1,153 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package serialkey
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Period is the length of time after which a periodic sequence restarts.
+type Period int
+
+const (
+	// Daily restarts sequences at the midnight.
+	Daily Period = iota + 1
+	// Monthly restarts sequences at the first day of the month.
+	Monthly
+	// Yearly restarts sequences at the first day of the year.
+	Yearly
+)
+
+// layout returns the time layout of the period suffix
+// or the empty string if the period is unknown.
+func (p Period) layout() string {
+	switch p {
+	case Daily:
+		return "2006-01-02"
+	case Monthly:
+		return "2006-01"
+	case Yearly:
+		return "2006"
+	default:
+		return ""
+	}
+}
+
+// NewPeriodic returns the serialkeys keychain which derives the effective
+// key name from the passed key name and the current period,
+// so the sequences restarts at the start number at each period boundary.
+// The period must be one of the daily, monthly or yearly periods.
+func NewPeriodic(chain Chain, period Period, opts ...PeriodicOption) (*Periodic, error) {
+	layout := period.layout()
+	if layout == "" {
+		return nil, fmt.Errorf("unknown period of the periodic chain: %d", period)
+	}
+
+	cfg := PeriodicConfiguration{
+		now:       time.Now,
+		location:  time.UTC,
+		separator: ":",
+	}
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return &Periodic{
+		chain:     chain,
+		layout:    layout,
+		now:       cfg.now,
+		location:  cfg.location,
+		separator: cfg.separator,
+	}, nil
+}
+
+// Periodic is the serialkeys keychain scoped to the day, month or year.
+type Periodic struct {
+	chain     Chain
+	layout    string
+	now       func() time.Time
+	location  *time.Location
+	separator string
+}
+
+// Next for the passed key name returns an value guaranteed to be greater
+// than the value returned for the same key name within the current period
+// passed at the time of previous call of the next method or the forward method.
+// The next method is thread safe if the underlying chain is thread safe.
+func (chain *Periodic) Next(ctx context.Context, key string) (int64, error) {
+	return chain.chain.Next(ctx, chain.Key(key, chain.now()))
+}
+
+// Last for the passed key name returns the value returned for
+// the same key name within the current period passed at the time
+// of previous call of the next method or the forward method.
+// The last method is thread safe if the underlying chain is thread safe.
+func (chain *Periodic) Last(ctx context.Context, key string) (int64, error) {
+	return chain.chain.Last(ctx, chain.Key(key, chain.now()))
+}
+
+// LastAt for the passed key name returns the last value issued within
+// the period which contains the passed time, so a previous period
+// may be queried.
+// The last at method is thread safe if the underlying chain is thread safe.
+func (chain *Periodic) LastAt(ctx context.Context, key string, at time.Time) (int64, error) {
+	return chain.chain.Last(ctx, chain.Key(key, at))
+}
+
+// Forward for the passed key name returns an value guaranteed
+// to be greater or equal to the target value and guaranteed to be greater
+// than the value returned for the same key name within the current period
+// passed at the time of previous call of the forward method or the next method.
+// The forward method is thread safe if the underlying chain is thread safe.
+func (chain *Periodic) Forward(ctx context.Context, key string, target int64) (int64, error) {
+	return chain.chain.Forward(ctx, chain.Key(key, chain.now()), target)
+}
+
+// Key returns the effective key name of the underlying chain
+// for the passed key name and the period which contains the passed time.
+func (chain *Periodic) Key(key string, at time.Time) string {
+	return key + chain.separator + at.In(chain.location).Format(chain.layout)
+}
+
+// Health checks the health of the underlying chain.
+// The health method is thread safe if the underlying chain is thread safe.
+func (chain *Periodic) Health(ctx context.Context) error {
+	return Health(ctx, chain.chain)
+}
+
+// Close closes the underlying chain.
+// The close method is thread safe if the underlying chain is thread safe.
+func (chain *Periodic) Close() error {
+	return chain.chain.Close()
+}
+
+// PeriodicOption changes configuration.
+type PeriodicOption func(*PeriodicConfiguration)
+
+// PeriodicConfiguration holds values changeable by options.
+type PeriodicConfiguration struct {
+	now       func() time.Time
+	location  *time.Location
+	separator string
+}
+
+// PeriodicWithClock sets the function returning the current time.
+func PeriodicWithClock(now func() time.Time) PeriodicOption {
+	return func(cfg *PeriodicConfiguration) { cfg.now = now }
+}
+
+// PeriodicWithLocation sets the time zone of the period boundaries.
+func PeriodicWithLocation(location *time.Location) PeriodicOption {
+	return func(cfg *PeriodicConfiguration) { cfg.location = location }
+}
+
+// PeriodicWithSeparator sets the separator between the key name
+// and the period suffix.
+func PeriodicWithSeparator(separator string) PeriodicOption {
+	return func(cfg *PeriodicConfiguration) { cfg.separator = separator }
+}
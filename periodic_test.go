@@ -0,0 +1,104 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package serialkey_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pfmt/serialkey"
+	"github.com/pfmt/serialkey/internal/serialkeytest"
+)
+
+func TestPeriodic(t *testing.T) {
+	chain, err := serialkey.NewPeriodic(serialkey.NewLocal(localOpt), serialkey.Daily)
+	if err != nil {
+		t.Fatalf("new periodic: %s", err)
+	}
+	serialkeytest.Next(t, chain)
+	closer.add(chain.Close)
+}
+
+func TestPeriodicRestart(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	now := time.Date(2022, time.December, 31, 23, 0, 0, 0, time.UTC)
+
+	chain, err := serialkey.NewPeriodic(
+		serialkey.NewLocal(localOpt),
+		serialkey.Yearly,
+		serialkey.PeriodicWithClock(func() time.Time { return now }),
+	)
+	if err != nil {
+		t.Fatalf("new periodic: %s", err)
+	}
+	defer chain.Close()
+
+	for i := 0; i < 3; i++ {
+		_, err := chain.Next(ctx, "invoice")
+		if err != nil {
+			t.Fatalf("next: %s", err)
+		}
+	}
+
+	prev := now
+	now = now.Add(2 * time.Hour)
+
+	got, err := chain.Next(ctx, "invoice")
+	if err != nil {
+		t.Fatalf("next: %s", err)
+	}
+	if got != 1 {
+		t.Errorf("want next value after the period boundary: 1, got: %d", got)
+	}
+
+	got, err = chain.LastAt(ctx, "invoice", prev)
+	if err != nil {
+		t.Fatalf("last at: %s", err)
+	}
+	if got != 3 {
+		t.Errorf("want last value of the previous period: 3, got: %d", got)
+	}
+
+	if key := chain.Key("invoice", prev); key != "invoice:2022" {
+		t.Errorf("want key: invoice:2022, got: %s", key)
+	}
+}
+
+func TestPeriodicLocation(t *testing.T) {
+	t.Parallel()
+
+	location := time.FixedZone("UTC+3", 3*60*60)
+	now := time.Date(2022, time.October, 31, 22, 0, 0, 0, time.UTC)
+
+	chain, err := serialkey.NewPeriodic(
+		serialkey.NewLocal(localOpt),
+		serialkey.Monthly,
+		serialkey.PeriodicWithLocation(location),
+	)
+	if err != nil {
+		t.Fatalf("new periodic: %s", err)
+	}
+	defer chain.Close()
+
+	if key := chain.Key("ticket", now); key != "ticket:2022-11" {
+		t.Errorf("want key: ticket:2022-11, got: %s", key)
+	}
+}
+
+func TestNewPeriodicUnknownPeriod(t *testing.T) {
+	t.Parallel()
+
+	for _, period := range []serialkey.Period{0, serialkey.Yearly + 1, -1} {
+		_, err := serialkey.NewPeriodic(serialkey.NewLocal(localOpt), period)
+		if err == nil {
+			t.Errorf("want unknown period %d error", period)
+		}
+	}
+}
@@ -8,24 +8,40 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// ErrSchemaMismatch is returned when the table does not exist
+// or its columns differ from the expected ones.
+var ErrSchemaMismatch = errors.New("schema mismatch")
+
 // NewPgxPool returns the serialkeys keychain based on the pgx pool.
 func NewPgxPool(pool *pgxpool.Pool, opts ...PgxPoolOption) *PgxPool {
-	cfg := PgxPoolConfiguration{table: Table}
+	cfg := PgxPoolConfiguration{table: Table, tracerProvider: otel.GetTracerProvider()}
 
 	for _, opt := range opts {
 		opt(&cfg)
 	}
 
 	return &PgxPool{
-		start: cfg.start,
-		table: cfg.table,
-		pool:  pool,
+		start:       cfg.start,
+		table:       cfg.table,
+		dialect:     cfg.dialect,
+		tracer:      cfg.tracerProvider.Tracer(TracerName),
+		logger:      cfg.logger,
+		retryPolicy: cfg.retryPolicy,
+		history:     cfg.history,
+		pool:        pool,
 	}
 }
 
@@ -34,6 +50,11 @@ type PgxPool struct {
 	sync.RWMutex
 	start        int64
 	table        string
+	dialect      Dialect
+	tracer       trace.Tracer
+	logger       *slog.Logger
+	retryPolicy  *RetryPolicy
+	history      bool
 	pool         *pgxpool.Pool
 	nextQuery    string
 	nextNQuery   string
@@ -61,27 +82,32 @@ func (chain *PgxPool) Next(ctx context.Context, key string) (int64, error) {
 	defer chain.Unlock()
 
 	if chain.nextQuery == "" {
-		q, err := PostgreSQL{Table: chain.table}.next()
+		db := PostgreSQL{Table: chain.table}
+
+		generate := db.next
+		if chain.history {
+			generate = db.nextHistory
+		}
+
+		q, err := generate()
 		if err != nil {
 			return 0, fmt.Errorf("generate the next value fetching query: %w", err)
 		}
 		chain.nextQuery = q
+		chain.log(ctx, slog.LevelDebug, "generate query", slog.String("query", "next"))
 	}
 
 	return chain.next(ctx, key)
 }
 
 func (chain *PgxPool) next(ctx context.Context, key string) (int64, error) {
-	conn, err := chain.conn(ctx)
-	if err != nil {
-		return 0, err
-	}
-	defer conn.Release()
-
 	var value int64
 
-	err = conn.QueryRow(ctx, chain.nextQuery, key, chain.start).Scan(&value)
+	err := chain.query(ctx, func(ctx context.Context, conn *pgxpool.Conn) error {
+		return conn.QueryRow(ctx, chain.nextQuery, chain.args(ctx, key, chain.start)...).Scan(&value)
+	})
 	if err != nil {
+		chain.log(ctx, slog.LevelError, "query failed", slog.String("query", "next"), slog.String("key", key), slog.Any("error", err))
 		return 0, fmt.Errorf("fetch next value %s: %w", key, err)
 	}
 
@@ -108,27 +134,32 @@ func (chain *PgxPool) NextN(ctx context.Context, key string, count int64) (int64
 	defer chain.Unlock()
 
 	if chain.nextNQuery == "" {
-		q, err := PostgreSQL{Table: chain.table}.nextN()
+		db := PostgreSQL{Table: chain.table}
+
+		generate := db.nextN
+		if chain.history {
+			generate = db.nextNHistory
+		}
+
+		q, err := generate()
 		if err != nil {
 			return 0, fmt.Errorf("generate the next N values fetching query: %w", err)
 		}
 		chain.nextNQuery = q
+		chain.log(ctx, slog.LevelDebug, "generate query", slog.String("query", "next_n"))
 	}
 
 	return chain.nextN(ctx, key, count)
 }
 
 func (chain *PgxPool) nextN(ctx context.Context, key string, count int64) (int64, error) {
-	conn, err := chain.conn(ctx)
-	if err != nil {
-		return 0, err
-	}
-	defer conn.Release()
-
 	var value int64
 
-	err = conn.QueryRow(ctx, chain.nextNQuery, key, count).Scan(&value)
+	err := chain.query(ctx, func(ctx context.Context, conn *pgxpool.Conn) error {
+		return conn.QueryRow(ctx, chain.nextNQuery, chain.args(ctx, key, count)...).Scan(&value)
+	})
 	if err != nil {
+		chain.log(ctx, slog.LevelError, "query failed", slog.String("query", "next_n"), slog.String("key", key), slog.Int64("count", count), slog.Any("error", err))
 		return 0, fmt.Errorf("fetch next values %s: %w", key, err)
 	}
 
@@ -158,25 +189,23 @@ func (chain *PgxPool) Last(ctx context.Context, key string) (int64, error) {
 			return 0, fmt.Errorf("generate the last value fetching query: %w", err)
 		}
 		chain.lastQuery = q
+		chain.log(ctx, slog.LevelDebug, "generate query", slog.String("query", "last"))
 	}
 
 	return chain.last(ctx, key)
 }
 
 func (chain *PgxPool) last(ctx context.Context, key string) (int64, error) {
-	conn, err := chain.conn(ctx)
-	if err != nil {
-		return 0, err
-	}
-	defer conn.Release()
-
 	var value int64
 
-	err = conn.QueryRow(ctx, chain.lastQuery, key).Scan(&value)
+	err := chain.query(ctx, func(ctx context.Context, conn *pgxpool.Conn) error {
+		return conn.QueryRow(ctx, chain.lastQuery, key).Scan(&value)
+	})
 	if errors.Is(err, pgx.ErrNoRows) {
 		return chain.start - 1, nil
 
 	} else if err != nil {
+		chain.log(ctx, slog.LevelError, "query failed", slog.String("query", "last"), slog.String("key", key), slog.Any("error", err))
 		return 0, fmt.Errorf("fetch last value %s: %w", key, err)
 	}
 
@@ -202,34 +231,45 @@ func (chain *PgxPool) Forward(ctx context.Context, key string, target int64) (in
 	defer chain.Unlock()
 
 	if chain.forwardQuery == "" {
-		q, err := PostgreSQL{Table: chain.table}.forward()
+		db := PostgreSQL{Table: chain.table}
+
+		generate := db.forward
+
+		if chain.history {
+			generate = db.forwardHistory
+		}
+
+		q, err := generate()
 		if err != nil {
 			return 0, fmt.Errorf("generate the forward value query: %w", err)
 		}
 		chain.forwardQuery = q
+		chain.log(ctx, slog.LevelDebug, "generate query", slog.String("query", "forward"))
 	}
 
 	return chain.forward(ctx, key, target)
 }
 
 func (chain *PgxPool) forward(ctx context.Context, key string, target int64) (int64, error) {
-	conn, err := chain.conn(ctx)
-	if err != nil {
-		return 0, err
-	}
-	defer conn.Release()
-
 	var value int64
 
-	err = conn.QueryRow(ctx, chain.forwardQuery, key, target).Scan(&value)
+	err := chain.query(ctx, func(ctx context.Context, conn *pgxpool.Conn) error {
+		return conn.QueryRow(ctx, chain.forwardQuery, chain.args(ctx, key, target)...).Scan(&value)
+	})
 	if err != nil {
+		chain.log(ctx, slog.LevelError, "query failed", slog.String("query", "forward"), slog.String("key", key), slog.Int64("target", target), slog.Any("error", err))
 		return 0, fmt.Errorf("forward value %s to %d: %w", key, target, err)
 	}
 
 	return value, nil
 }
 
-// CreateTable creates the PostgreSQL table if not exists.
+// CreateTable creates the PostgreSQL table, the sibling lease table
+// and the history table if the history is enabled, if not exists
+// and verifies the columns of the table afterwards.
+// The tables are created in the single transaction holding
+// the advisory lock keyed by the table name,
+// so the concurrent instances do not race.
 // The create table method is thread safe.
 func (chain *PgxPool) CreateTable(ctx context.Context) error {
 	chain.RLock()
@@ -244,36 +284,386 @@ func (chain *PgxPool) CreateTable(ctx context.Context) error {
 	defer chain.Unlock()
 
 	if !chain.created {
-		q, err := PostgreSQL{Table: chain.table}.createTable()
+		db := PostgreSQL{Table: chain.table}
+
+		create, err := db.createTable()
 		if err != nil {
 			return fmt.Errorf("generate the table creation query: %w", err)
 		}
 
-		conn, err := chain.conn(ctx)
+		createLease, err := db.createLeaseTable()
 		if err != nil {
-			return err
+			return fmt.Errorf("generate the lease table creation query: %w", err)
+		}
+
+		createHistory, err := db.historyCreate()
+		if err != nil {
+			return fmt.Errorf("generate the history table creation query: %w", err)
+		}
+
+		columns, err := db.columns()
+		if err != nil {
+			return fmt.Errorf("generate the columns query: %w", err)
 		}
-		defer conn.Release()
 
-		_, err = conn.Exec(ctx, q)
+		err = chain.query(ctx, func(ctx context.Context, conn *pgxpool.Conn) error {
+			err := chain.locked(ctx, conn, func(tx pgx.Tx) error {
+				_, err := tx.Exec(ctx, create)
+				if err != nil {
+					return fmt.Errorf("execute the table creation query: %w", err)
+				}
+
+				_, err = tx.Exec(ctx, createLease)
+				if err != nil {
+					return fmt.Errorf("execute the lease table creation query: %w", err)
+				}
+
+				if chain.history {
+					_, err = tx.Exec(ctx, createHistory)
+					if err != nil {
+						return fmt.Errorf("execute the history table creation query: %w", err)
+					}
+				}
+
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			return chain.verify(ctx, conn, columns)
+		})
 		if err != nil {
-			return fmt.Errorf("execute the table creation query: %w", err)
+			return fmt.Errorf("create table: %w", err)
 		}
 
 		chain.created = true
+		chain.log(ctx, slog.LevelInfo, "create table")
 	}
 
 	return nil
 }
 
+// DropTable drops the PostgreSQL table and the sibling tables if exists
+// in the transaction holding the advisory lock keyed by the table name.
+// The drop table method is thread safe.
+func (chain *PgxPool) DropTable(ctx context.Context) error {
+	chain.Lock()
+	defer chain.Unlock()
+
+	q, err := PostgreSQL{Table: chain.table}.dropTable()
+	if err != nil {
+		return fmt.Errorf("generate the table dropping query: %w", err)
+	}
+
+	err = chain.query(ctx, func(ctx context.Context, conn *pgxpool.Conn) error {
+		return chain.locked(ctx, conn, func(tx pgx.Tx) error {
+			_, err := tx.Exec(ctx, q)
+			return err
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("execute the table dropping query: %w", err)
+	}
+
+	chain.created = false
+	chain.log(ctx, slog.LevelInfo, "drop table")
+
+	return nil
+}
+
+// Durable reports the chain is durable, the values are stored in the table.
+// The durable method is thread safe.
+func (*PgxPool) Durable() bool {
+	return true
+}
+
+// HealthKey is the key name written by the health checks of the chains.
+const HealthKey = "serialkey.health"
+
+// Health checks the connectivity, verifies the columns of the table
+// and confirms the write permission by the dry-run upsert
+// in the rolled back transaction.
+// The health method is thread safe.
+func (chain *PgxPool) Health(ctx context.Context) error {
+	db := PostgreSQL{Table: chain.table}
+
+	columns, err := db.columns()
+	if err != nil {
+		return fmt.Errorf("generate the columns query: %w", err)
+	}
+
+	next, err := db.next()
+	if err != nil {
+		return fmt.Errorf("generate the next value fetching query: %w", err)
+	}
+
+	err = chain.query(ctx, func(ctx context.Context, conn *pgxpool.Conn) error {
+		err := conn.Ping(ctx)
+		if err != nil {
+			return fmt.Errorf("ping: %w", err)
+		}
+
+		err = chain.verify(ctx, conn, columns)
+		if err != nil {
+			return err
+		}
+
+		tx, err := conn.Begin(ctx)
+		if err != nil {
+			return fmt.Errorf("begin: %w", err)
+		}
+		defer func() { _ = tx.Rollback(ctx) }()
+
+		var value int64
+
+		err = tx.QueryRow(ctx, next, HealthKey, chain.start).Scan(&value)
+		if err != nil {
+			return fmt.Errorf("dry-run upsert: %w", err)
+		}
+
+		err = tx.Rollback(ctx)
+		if err != nil {
+			return fmt.Errorf("rollback: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		chain.log(ctx, slog.LevelError, "health check failed", slog.Any("error", err))
+		return fmt.Errorf("check health: %w", err)
+	}
+
+	return nil
+}
+
+// pgxPoolColumns are the columns expected in the table.
+var pgxPoolColumns = []struct {
+	name    string
+	typ     string
+	notNull bool
+}{
+	{name: "key", typ: "text", notNull: true},
+	{name: "value", typ: "bigint", notNull: true},
+	{name: "created_at", typ: "timestamp with time zone", notNull: true},
+	{name: "updated_at", typ: "timestamp with time zone"},
+}
+
+// verify returns the error if the table does not exist
+// or the expected columns are missing or differ from the expected ones,
+// the additional columns are allowed.
+func (chain *PgxPool) verify(ctx context.Context, conn *pgxpool.Conn, query string) error {
+	rows, err := conn.Query(ctx, query, chain.table)
+	if err != nil {
+		return fmt.Errorf("fetch columns: %w", err)
+	}
+	defer rows.Close()
+
+	type column struct {
+		typ     string
+		notNull bool
+	}
+
+	got := make(map[string]column)
+
+	for rows.Next() {
+		var (
+			name string
+			c    column
+		)
+
+		err = rows.Scan(&name, &c.typ, &c.notNull)
+		if err != nil {
+			return fmt.Errorf("scan column: %w", err)
+		}
+
+		got[name] = c
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("fetch columns: %w", err)
+	}
+
+	if len(got) == 0 {
+		return fmt.Errorf("table %s does not exist: %w", chain.table, ErrSchemaMismatch)
+	}
+
+	var mismatches []string
+
+	for _, want := range pgxPoolColumns {
+		c, ok := got[want.name]
+
+		switch {
+		case !ok:
+			mismatches = append(mismatches, fmt.Sprintf("missing column %s", want.name))
+		case c.typ != want.typ:
+			mismatches = append(mismatches, fmt.Sprintf("column %s type %s, want %s", want.name, c.typ, want.typ))
+		case want.notNull && !c.notNull:
+			mismatches = append(mismatches, fmt.Sprintf("column %s is nullable", want.name))
+		}
+	}
+
+	if len(mismatches) != 0 {
+		return fmt.Errorf("table %s: %s: %w", chain.table, strings.Join(mismatches, ", "), ErrSchemaMismatch)
+	}
+
+	return nil
+}
+
+// Record is the state of the sequence stored in the PostgreSQL table.
+type Record struct {
+	Key       string
+	Value     int64
+	CreatedAt time.Time
+	UpdatedAt *time.Time
+}
+
+// List returns the records of all of the sequences ordered by the key name.
+// The list method is thread safe.
+func (chain *PgxPool) List(ctx context.Context) ([]Record, error) {
+	q, err := PostgreSQL{Table: chain.table}.list()
+	if err != nil {
+		return nil, fmt.Errorf("generate the list query: %w", err)
+	}
+
+	conn, err := chain.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, q)
+	if err != nil {
+		return nil, fmt.Errorf("list values: %w", err)
+	}
+	defer rows.Close()
+
+	var records []Record
+
+	for rows.Next() {
+		var r Record
+
+		err = rows.Scan(&r.Key, &r.Value, &r.CreatedAt, &r.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("scan value: %w", err)
+		}
+
+		records = append(records, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list values: %w", err)
+	}
+
+	return records, nil
+}
+
+// Reset deletes the sequence of the passed key name,
+// so the next value restarts at the start number.
+// The reset method is thread safe.
+func (chain *PgxPool) Reset(ctx context.Context, key string) error {
+	db := PostgreSQL{Table: chain.table}
+
+	generate := db.reset
+	if chain.history {
+		generate = db.resetHistory
+	}
+
+	q, err := generate()
+	if err != nil {
+		return fmt.Errorf("generate the reset query: %w", err)
+	}
+
+	err = chain.query(ctx, func(ctx context.Context, conn *pgxpool.Conn) error {
+		_, err := conn.Exec(ctx, q, chain.args(ctx, key)...)
+		return err
+	})
+	if err != nil {
+		chain.log(ctx, slog.LevelError, "query failed", slog.String("query", "reset"), slog.String("key", key), slog.Any("error", err))
+		return fmt.Errorf("reset value %s: %w", key, err)
+	}
+
+	return nil
+}
+
+func (chain *PgxPool) log(ctx context.Context, level slog.Level, msg string, attrs ...slog.Attr) {
+	if chain.logger == nil {
+		return
+	}
+	chain.logger.LogAttrs(ctx, level, msg, append(attrs, slog.String("table", chain.table))...)
+}
+
+// args returns the arguments of the query
+// appended by the actor of the history if the history is enabled.
+func (chain *PgxPool) args(ctx context.Context, args ...any) []any {
+	if chain.history {
+		return append(args, Actor(ctx))
+	}
+	return args
+}
+
+// Table returns the table name.
+func (chain *PgxPool) Table() string {
+	return chain.table
+}
+
+// Stat returns the statistics of the underlying pgx pool.
+// The stat method is thread safe.
+func (chain *PgxPool) Stat() *pgxpool.Stat {
+	return chain.pool.Stat()
+}
+
 func (chain *PgxPool) conn(ctx context.Context) (*pgxpool.Conn, error) {
+	ctx, span := chain.tracer.Start(ctx, "serialkey.PgxPool.acquire")
+	defer span.End()
+
 	conn, err := chain.pool.Acquire(ctx)
 	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
 		return nil, fmt.Errorf("acquire connection: %w", err)
 	}
 	return conn, nil
 }
 
+// query calls the function executing the query on the acquired connection
+// within the span and retries the call by the retry policy,
+// each of the retries acquires the connection once again,
+// so the broken connection is not reused.
+func (chain *PgxPool) query(ctx context.Context, f func(context.Context, *pgxpool.Conn) error) error {
+	return chain.retry(ctx, func() error {
+		conn, err := chain.conn(ctx)
+		if err != nil {
+			return err
+		}
+		defer conn.Release()
+
+		ctx, span := chain.tracer.Start(ctx, "serialkey.PgxPool.query", trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("serialkey.table", chain.table),
+			attribute.String("serialkey.dialect", chain.dialect.String()),
+		))
+		defer span.End()
+
+		err = f(ctx, conn)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+
+		return err
+	})
+}
+
+// retry calls the function and retries the call by the retry policy
+// or if the retry policy is not set, by the dialect.
+func (chain *PgxPool) retry(ctx context.Context, f func() error) error {
+	if chain.retryPolicy != nil {
+		return chain.retryPolicy.retry(ctx, f)
+	}
+	return chain.dialect.retry(ctx, f)
+}
+
 // Close closes pgx pool.
 // The close method is thread safe.
 func (chain *PgxPool) Close() error {
@@ -294,6 +684,7 @@ func (chain *PgxPool) Close() error {
 
 	chain.pool.Close()
 	chain.closed = true
+	chain.log(context.Background(), slog.LevelInfo, "close", slog.String("url", RedactURL(chain.pool.Config().ConnString())))
 
 	return nil
 }
@@ -303,8 +694,13 @@ type PgxPoolOption func(*PgxPoolConfiguration)
 
 // PgxPoolConfiguration holds values changeable by options.
 type PgxPoolConfiguration struct {
-	start int64
-	table string
+	start          int64
+	table          string
+	dialect        Dialect
+	tracerProvider trace.TracerProvider
+	logger         *slog.Logger
+	retryPolicy    *RetryPolicy
+	history        bool
 }
 
 // PgxPoolWithStart sets the start number.
@@ -316,3 +712,36 @@ func PgxPoolWithStart(start int64) PgxPoolOption {
 func PgxPoolWithTable(table string) PgxPoolOption {
 	return func(cfg *PgxPoolConfiguration) { cfg.table = table }
 }
+
+// PgxPoolWithDialect sets the SQL dialect of the PostgreSQL compatible database.
+func PgxPoolWithDialect(dialect Dialect) PgxPoolOption {
+	return func(cfg *PgxPoolConfiguration) { cfg.dialect = dialect }
+}
+
+// PgxPoolWithTracerProvider sets the OpenTelemetry tracer provider
+// of the spans of the connection acquires and the queries.
+func PgxPoolWithTracerProvider(tracerProvider trace.TracerProvider) PgxPoolOption {
+	return func(cfg *PgxPoolConfiguration) { cfg.tracerProvider = tracerProvider }
+}
+
+// PgxPoolWithLogger sets the logger of the query generation,
+// the query errors, the table creation and the closing.
+func PgxPoolWithLogger(logger *slog.Logger) PgxPoolOption {
+	return func(cfg *PgxPoolConfiguration) { cfg.logger = logger }
+}
+
+// PgxPoolWithRetryPolicy sets the policy of the retries of the queries
+// failed by the transient errors, the retry policy replaces the retries
+// of the serialization failures of the dialect.
+// Retrying the next method may skip values but never duplicates them.
+func PgxPoolWithRetryPolicy(policy RetryPolicy) PgxPoolOption {
+	return func(cfg *PgxPoolConfiguration) { cfg.retryPolicy = &policy }
+}
+
+// PgxPoolWithHistory enables the history of the changes of the sequences
+// written to the history table by the same statement as the changes,
+// the history table is created by the create table method
+// or by the migrations.
+func PgxPoolWithHistory(history bool) PgxPoolOption {
+	return func(cfg *PgxPoolConfiguration) { cfg.history = history }
+}
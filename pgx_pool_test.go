@@ -9,10 +9,18 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strings"
+	"sync"
 	"testing"
 
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgproto3"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/pfmt/serialkey"
+	"github.com/pfmt/serialkey/internal/serialkeytest"
+	"github.com/pfmt/serialkey/serialkeyotel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 var (
@@ -28,7 +36,8 @@ func TestPgx(t *testing.T) {
 	}
 
 	chain := serialkey.NewPgxPool(pgxPool, pgxOpt)
-	serailKeyTest(t, chain)
+	serialkeytest.Next(t, chain)
+	serialkeytest.Forward(t, chain)
 	closer.add(chain.Close)
 }
 
@@ -39,7 +48,7 @@ func BenchmarkPgxNext(b *testing.B) {
 	}
 
 	chain := serialkey.NewPgxPool(pgxPool, pgxOpt)
-	nextSerailKeyBenchmark(b, chain)
+	serialkeytest.BenchmarkNext(b, chain)
 	closer.add(chain.Close)
 }
 
@@ -56,3 +65,459 @@ func NewPgxPool(ctx context.Context) (*pgxpool.Pool, error) {
 
 	return pool, nil
 }
+
+func TestPgxPoolTracing(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+
+	var queries int32
+
+	url := newFaultServer(t, 0, &queries)
+
+	pool, err := pgxpool.New(ctx, url)
+	if err != nil {
+		t.Fatalf("pgx connect %s: %s", url, err)
+	}
+
+	chain := serialkeyotel.New(
+		serialkey.NewPgxPool(pool, pgxOpt, serialkey.PgxPoolWithTracerProvider(provider)),
+		serialkeyotel.WithTracerProvider(provider),
+	)
+	t.Cleanup(func() { _ = chain.Close() })
+
+	_, err = chain.Next(ctx, "foo")
+	if err != nil {
+		t.Fatalf("next: %s", err)
+	}
+
+	spans := exporter.GetSpans()
+
+	want := []string{"serialkey.PgxPool.acquire", "serialkey.PgxPool.query", "serialkey.Next"}
+
+	if len(spans) != len(want) {
+		t.Fatalf("want spans: %v, got: %d", want, len(spans))
+	}
+
+	parent := spans[len(spans)-1].SpanContext.SpanID()
+
+	for i, name := range want {
+		if spans[i].Name != name {
+			t.Errorf("want span name: %s, got: %s", name, spans[i].Name)
+		}
+		if i < len(want)-1 && spans[i].Parent.SpanID() != parent {
+			t.Errorf("want span %s parent: %s, got: %s", name, parent, spans[i].Parent.SpanID())
+		}
+	}
+
+	var table bool
+
+	for _, attr := range spans[len(spans)-1].Attributes {
+		if attr.Key == "serialkey.table" && attr.Value.AsString() == serialkey.Table {
+			table = true
+		}
+	}
+
+	if !table {
+		t.Errorf("want span table attribute: %s, got: %v", serialkey.Table, spans[len(spans)-1].Attributes)
+	}
+}
+
+var pgxPoolHealthTests = []struct {
+	name    string
+	line    string
+	columns [][3]string
+	code    string
+	err     error
+}{
+	{
+		name: "healthy",
+		line: testline(),
+		columns: [][3]string{
+			{"key", "text", "t"},
+			{"value", "bigint", "t"},
+			{"created_at", "timestamp with time zone", "t"},
+			{"updated_at", "timestamp with time zone", "f"},
+			{"tenant", "text", "f"},
+		},
+	},
+	{
+		name: "missing table",
+		line: testline(),
+		err:  serialkey.ErrSchemaMismatch,
+	},
+	{
+		name: "schema mismatch",
+		line: testline(),
+		columns: [][3]string{
+			{"key", "text", "t"},
+			{"value", "integer", "t"},
+			{"created_at", "timestamp with time zone", "t"},
+		},
+		err: serialkey.ErrSchemaMismatch,
+	},
+	{
+		name: "write permission denied",
+		line: testline(),
+		columns: [][3]string{
+			{"key", "text", "t"},
+			{"value", "bigint", "t"},
+			{"created_at", "timestamp with time zone", "t"},
+			{"updated_at", "timestamp with time zone", "f"},
+		},
+		code: "42501",
+	},
+}
+
+func TestPgxPoolHealth(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range pgxPoolHealthTests {
+		tt := tt
+
+		t.Run(tt.line+"/"+tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			var (
+				mu      sync.Mutex
+				queries []string
+			)
+
+			url := newFakeServer(t, func(backend *pgproto3.Backend, query string) bool {
+				mu.Lock()
+				queries = append(queries, strings.Fields(query + " -")[0])
+				mu.Unlock()
+
+				switch {
+				case strings.Contains(query, "pg_attribute"):
+					sendColumns(backend, tt.columns)
+				case strings.HasPrefix(query, "INSERT") && tt.code != "":
+					backend.Send(&pgproto3.ErrorResponse{Severity: "ERROR", Code: tt.code, Message: "permission denied"})
+				case strings.HasPrefix(query, "INSERT"):
+					sendValue(backend, 1)
+				case strings.HasPrefix(query, "--"):
+					backend.Send(&pgproto3.EmptyQueryResponse{})
+				default:
+					backend.Send(&pgproto3.CommandComplete{CommandTag: []byte(strings.ToUpper(query))})
+				}
+
+				return true
+			})
+
+			pool, err := pgxpool.New(ctx, url)
+			if err != nil {
+				t.Fatalf("pgx connect %s: %s", url, err)
+			}
+
+			chain := serialkey.NewPgxPool(pool, pgxOpt)
+			t.Cleanup(func() { _ = chain.Close() })
+
+			err = chain.Health(ctx)
+
+			var pgErr *pgconn.PgError
+
+			switch {
+			case tt.code != "":
+				if !errors.As(err, &pgErr) || pgErr.Code != tt.code {
+					t.Errorf("want error code: %s, got: %v", tt.code, err)
+				}
+			case tt.err != nil:
+				if !errors.Is(err, tt.err) {
+					t.Errorf("want error: %s, got: %v", tt.err, err)
+				}
+			case err != nil:
+				t.Fatalf("health: %s", err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			for _, q := range queries {
+				if strings.EqualFold(q, "commit") {
+					t.Errorf("want the dry-run upsert rolled back, got queries: %v", queries)
+				}
+			}
+
+			if tt.err == nil && !strings.EqualFold(queries[len(queries)-1], "rollback") {
+				t.Errorf("want the dry-run upsert rolled back, got queries: %v", queries)
+			}
+		})
+	}
+}
+
+var pgxPoolCreateTableTests = []struct {
+	name    string
+	line    string
+	dialect serialkey.Dialect
+	columns [][3]string
+	want    []string
+	err     error
+}{
+	{
+		name: "postgresql",
+		line: testline(),
+		columns: [][3]string{
+			{"key", "text", "t"},
+			{"value", "bigint", "t"},
+			{"created_at", "timestamp with time zone", "t"},
+			{"updated_at", "timestamp with time zone", "f"},
+		},
+		want: []string{"begin", "select", "create", "create", "commit", "select"},
+	},
+	{
+		name:    "cockroachdb",
+		line:    testline(),
+		dialect: serialkey.DialectCockroachDB,
+		columns: [][3]string{
+			{"key", "text", "t"},
+			{"value", "bigint", "t"},
+			{"created_at", "timestamp with time zone", "t"},
+			{"updated_at", "timestamp with time zone", "f"},
+		},
+		want: []string{"begin", "create", "create", "commit", "select"},
+	},
+	{
+		name: "schema mismatch",
+		line: testline(),
+		columns: [][3]string{
+			{"key", "text", "t"},
+			{"value", "integer", "t"},
+			{"created_at", "timestamp with time zone", "t"},
+		},
+		want: []string{"begin", "select", "create", "create", "commit", "select"},
+		err:  serialkey.ErrSchemaMismatch,
+	},
+}
+
+func TestPgxPoolCreateTable(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range pgxPoolCreateTableTests {
+		tt := tt
+
+		t.Run(tt.line+"/"+tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			var (
+				mu      sync.Mutex
+				queries []string
+			)
+
+			url := newFakeServer(t, func(backend *pgproto3.Backend, query string) bool {
+				mu.Lock()
+				queries = append(queries, strings.ToLower(strings.Fields(query + " -")[0]))
+				mu.Unlock()
+
+				if strings.Contains(query, "pg_attribute") {
+					sendColumns(backend, tt.columns)
+				} else {
+					backend.Send(&pgproto3.CommandComplete{CommandTag: []byte(strings.ToUpper(strings.Fields(query + " -")[0]))})
+				}
+
+				return true
+			})
+
+			pool, err := pgxpool.New(ctx, url)
+			if err != nil {
+				t.Fatalf("pgx connect %s: %s", url, err)
+			}
+
+			chain := serialkey.NewPgxPool(pool, pgxOpt, serialkey.PgxPoolWithDialect(tt.dialect))
+			t.Cleanup(func() { _ = chain.Close() })
+
+			err = chain.CreateTable(ctx)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Errorf("want error: %s, got: %v", tt.err, err)
+				}
+			} else if err != nil {
+				t.Fatalf("create table: %s", err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if strings.Join(queries, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("want queries: %v, got: %v", tt.want, queries)
+			}
+		})
+	}
+}
+
+func TestPgxPoolList(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	url := newFakeServer(t, func(backend *pgproto3.Backend, query string) bool {
+		if !strings.HasPrefix(query, "SELECT key, value, created_at, updated_at FROM serialkeys ") {
+			return false
+		}
+
+		backend.Send(&pgproto3.RowDescription{Fields: []pgproto3.FieldDescription{
+			{Name: []byte("key"), DataTypeOID: 25, DataTypeSize: -1, TypeModifier: -1},
+			{Name: []byte("value"), DataTypeOID: 20, DataTypeSize: 8, TypeModifier: -1},
+			{Name: []byte("created_at"), DataTypeOID: 1184, DataTypeSize: 8, TypeModifier: -1},
+			{Name: []byte("updated_at"), DataTypeOID: 1184, DataTypeSize: 8, TypeModifier: -1},
+		}})
+		backend.Send(&pgproto3.DataRow{Values: [][]byte{
+			[]byte("bar"), []byte("42"), []byte("2022-01-01 00:00:00+00"), nil,
+		}})
+		backend.Send(&pgproto3.DataRow{Values: [][]byte{
+			[]byte("foo"), []byte("1"), []byte("2022-01-01 00:00:00+00"), []byte("2022-01-02 00:00:00+00"),
+		}})
+		backend.Send(&pgproto3.CommandComplete{CommandTag: []byte("SELECT 2")})
+
+		return true
+	})
+
+	pool, err := pgxpool.New(ctx, url)
+	if err != nil {
+		t.Fatalf("pgx connect %s: %s", url, err)
+	}
+
+	chain := serialkey.NewPgxPool(pool, pgxOpt)
+	t.Cleanup(func() { _ = chain.Close() })
+
+	records, err := chain.List(ctx)
+	if err != nil {
+		t.Fatalf("list: %s", err)
+	}
+
+	if len(records) != 2 {
+		t.Fatalf("want records: 2, got: %d", len(records))
+	}
+
+	if r := records[0]; r.Key != "bar" || r.Value != 42 || r.CreatedAt.IsZero() || r.UpdatedAt != nil {
+		t.Errorf("unexpected record of the created sequence: %+v", r)
+	}
+
+	if r := records[1]; r.Key != "foo" || r.Value != 1 || r.UpdatedAt == nil || !r.UpdatedAt.After(r.CreatedAt) {
+		t.Errorf("unexpected record of the updated sequence: %+v", r)
+	}
+}
+
+func TestPgxPoolReset(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var (
+		mu      sync.Mutex
+		queries []string
+	)
+
+	url := newFakeServer(t, func(backend *pgproto3.Backend, query string) bool {
+		mu.Lock()
+		queries = append(queries, query)
+		mu.Unlock()
+
+		backend.Send(&pgproto3.CommandComplete{CommandTag: []byte("DELETE 1")})
+
+		return true
+	})
+
+	pool, err := pgxpool.New(ctx, url)
+	if err != nil {
+		t.Fatalf("pgx connect %s: %s", url, err)
+	}
+
+	chain := serialkey.NewPgxPool(pool, pgxOpt)
+	t.Cleanup(func() { _ = chain.Close() })
+
+	err = chain.Reset(ctx, "foo")
+	if err != nil {
+		t.Fatalf("reset: %s", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(queries) != 1 || !strings.HasPrefix(queries[0], "DELETE FROM serialkeys WHERE key = 'foo'") {
+		t.Errorf("want the sequence deleted, got queries: %q", queries)
+	}
+}
+
+func TestPgxPoolDropTable(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var (
+		mu      sync.Mutex
+		queries []string
+	)
+
+	url := newFakeServer(t, func(backend *pgproto3.Backend, query string) bool {
+		mu.Lock()
+		queries = append(queries, query)
+		mu.Unlock()
+
+		backend.Send(&pgproto3.CommandComplete{CommandTag: []byte(strings.ToUpper(strings.Fields(query + " -")[0]))})
+
+		return true
+	})
+
+	pool, err := pgxpool.New(ctx, url)
+	if err != nil {
+		t.Fatalf("pgx connect %s: %s", url, err)
+	}
+
+	chain := serialkey.NewPgxPool(pool, pgxOpt)
+	t.Cleanup(func() { _ = chain.Close() })
+
+	err = chain.DropTable(ctx)
+	if err != nil {
+		t.Fatalf("drop table: %s", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	var drop string
+	for _, q := range queries {
+		if strings.HasPrefix(q, "DROP") {
+			drop = q
+		}
+	}
+
+	for _, table := range []string{"serialkeys_history", "serialkeys_schema_version", "serialkeys_leases", "serialkeys;"} {
+		if !strings.Contains(drop, "DROP TABLE IF EXISTS "+table) {
+			t.Errorf("want table dropped: %s, got queries: %q", table, queries)
+		}
+	}
+
+	if len(queries) == 0 || !strings.EqualFold(queries[0], "begin") {
+		t.Errorf("want the tables dropped within the transaction, got queries: %q", queries)
+	}
+}
+
+// sendColumns sends the rows of the names, the types
+// and the not null constraints of the columns.
+func sendColumns(backend *pgproto3.Backend, columns [][3]string) {
+	backend.Send(&pgproto3.RowDescription{Fields: []pgproto3.FieldDescription{
+		{Name: []byte("attname"), DataTypeOID: 25, DataTypeSize: -1, TypeModifier: -1},
+		{Name: []byte("format_type"), DataTypeOID: 25, DataTypeSize: -1, TypeModifier: -1},
+		{Name: []byte("attnotnull"), DataTypeOID: 16, DataTypeSize: 1, TypeModifier: -1},
+	}})
+
+	for _, c := range columns {
+		backend.Send(&pgproto3.DataRow{Values: [][]byte{[]byte(c[0]), []byte(c[1]), []byte(c[2])}})
+	}
+
+	backend.Send(&pgproto3.CommandComplete{CommandTag: []byte(fmt.Sprintf("SELECT %d", len(columns)))})
+}
@@ -0,0 +1,404 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package serialkey
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// MaxSequenceLength is the maximum length of the PostgreSQL sequence name.
+const MaxSequenceLength = 63
+
+// SequencePrefix is the default prefix of the sequence names,
+// distinct from the names of the tables prefixed by the table name.
+const SequencePrefix = Table + "_seq_"
+
+// NewPgxSequence returns the serialkeys keychain based on the native
+// PostgreSQL sequences, each key is the sequence created at the time
+// of the first call for the key name.
+func NewPgxSequence(pool *pgxpool.Pool, opts ...PgxSequenceOption) *PgxSequence {
+	cfg := PgxSequenceConfiguration{
+		prefix:     SequencePrefix,
+		increment:  1,
+		increments: make(map[string]int64),
+	}
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return &PgxSequence{
+		start:      cfg.start,
+		prefix:     cfg.prefix,
+		increment:  cfg.increment,
+		increments: cfg.increments,
+		pool:       pool,
+		queries:    make(map[string]*pgxSequenceQueries),
+	}
+}
+
+// PgxSequence is the serialkeys keychain based on the native
+// PostgreSQL sequences, which avoids the row lock contention
+// and the bloat of the frequently updated table.
+type PgxSequence struct {
+	sync.RWMutex
+	start      int64
+	prefix     string
+	increment  int64
+	increments map[string]int64
+	pool       *pgxpool.Pool
+	queries    map[string]*pgxSequenceQueries
+	closed     bool
+}
+
+// pgxSequenceQueries is the set of the queries generated for the key name.
+type pgxSequenceQueries struct {
+	key     int64
+	create  string
+	lock    string
+	next    string
+	nextN   string
+	last    string
+	forward string
+}
+
+// Next for the passed key name returns an value guaranteed to be greater
+// than the value returned for the same key name passed at the time
+// of previous call of the next method or the forward method.
+// The next method is thread safe.
+func (chain *PgxSequence) Next(ctx context.Context, key string) (int64, error) {
+	q, err := chain.query(key)
+	if err != nil {
+		return 0, err
+	}
+
+	var value int64
+
+	err = chain.retry(ctx, q, func(conn *pgxpool.Conn) error {
+		return conn.QueryRow(ctx, q.next, q.key).Scan(&value)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("fetch next value %s: %w", key, err)
+	}
+
+	return value, nil
+}
+
+// NextN for the passed key name reserves the count of consecutive values
+// of the sequence and returns the last one of them.
+// The next N method is thread safe.
+func (chain *PgxSequence) NextN(ctx context.Context, key string, count int64) (int64, error) {
+	if count <= 0 {
+		return 0, fmt.Errorf("fetch next values %s: count must be positive: %d: %w", key, count, ErrInvalidRequest)
+	}
+
+	q, err := chain.query(key)
+	if err != nil {
+		return 0, err
+	}
+
+	var value int64
+
+	err = chain.retry(ctx, q, func(conn *pgxpool.Conn) error {
+		return chain.locked(ctx, conn, q, func(tx pgx.Tx) error {
+			return tx.QueryRow(ctx, q.nextN, count).Scan(&value)
+		})
+	})
+	if err != nil {
+		return 0, fmt.Errorf("fetch next values %s: %w", key, err)
+	}
+
+	return value, nil
+}
+
+// Last for the passed key name returns the value returned for
+// the same key name passed at the time of previous call
+// of the next method or the forward method.
+// The last method is thread safe.
+func (chain *PgxSequence) Last(ctx context.Context, key string) (int64, error) {
+	q, err := chain.query(key)
+	if err != nil {
+		return 0, err
+	}
+
+	conn, err := chain.conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Release()
+
+	var (
+		value  int64
+		called bool
+	)
+
+	err = conn.QueryRow(ctx, q.last).Scan(&value, &called)
+	if isUndefinedTable(err) {
+		return chain.start - 1, nil
+
+	} else if err != nil {
+		return 0, fmt.Errorf("fetch last value %s: %w", key, err)
+	}
+
+	if !called {
+		return chain.start - 1, nil
+	}
+
+	return value, nil
+}
+
+// Forward for the passed key name returns an value guaranteed
+// to be greater or equal to the target value and guaranteed to be greater
+// than the value returned for the same key name passed at the time
+// of previous call of the forward method or the next method.
+// The forward method is thread safe.
+func (chain *PgxSequence) Forward(ctx context.Context, key string, target int64) (int64, error) {
+	q, err := chain.query(key)
+	if err != nil {
+		return 0, err
+	}
+
+	var value int64
+
+	err = chain.retry(ctx, q, func(conn *pgxpool.Conn) error {
+		return chain.locked(ctx, conn, q, func(tx pgx.Tx) error {
+			return tx.QueryRow(ctx, q.forward, target).Scan(&value)
+		})
+	})
+	if err != nil {
+		return 0, fmt.Errorf("forward value %s to %d: %w", key, target, err)
+	}
+
+	return value, nil
+}
+
+// Sequence returns the name of the PostgreSQL sequence of the key name,
+// the prefix and the key name are lowercased and the characters other than
+// the latin letters, the digits and the underscore are replaced
+// by the underscore, the name starting with the digit is prefixed
+// by the underscore, the changed or the truncated names are suffixed
+// by the hash of the key name to keep the names of the different keys distinct.
+func (chain *PgxSequence) Sequence(key string) string {
+	var b strings.Builder
+
+	for i, r := range strings.ToLower(chain.prefix + key) {
+		if i == 0 && r >= '0' && r <= '9' {
+			b.WriteByte('_')
+		}
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_' {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+
+	name := b.String()
+
+	if name == chain.prefix+key && len(name) <= MaxSequenceLength {
+		return name
+	}
+
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	suffix := fmt.Sprintf("_%08x", h.Sum32())
+
+	if len(name) > MaxSequenceLength-len(suffix) {
+		name = name[:MaxSequenceLength-len(suffix)]
+	}
+
+	return name + suffix
+}
+
+// query returns the queries of the key name generated at the first call.
+func (chain *PgxSequence) query(key string) (*pgxSequenceQueries, error) {
+	chain.RLock()
+	q, ok := chain.queries[key]
+	chain.RUnlock()
+
+	if ok {
+		return q, nil
+	}
+
+	chain.Lock()
+	defer chain.Unlock()
+
+	if q, ok := chain.queries[key]; ok {
+		return q, nil
+	}
+
+	increment, ok := chain.increments[key]
+	if !ok {
+		increment = chain.increment
+	}
+
+	db := PostgreSQL{Sequence: chain.Sequence(key), Start: chain.start, Increment: increment}
+
+	q = &pgxSequenceQueries{key: advisoryLockKey(db.Sequence)}
+
+	for _, g := range []struct {
+		query    *string
+		generate func() (string, error)
+		name     string
+	}{
+		{query: &q.create, generate: db.sequenceCreate, name: "sequence creation"},
+		{query: &q.lock, generate: db.advisoryLock, name: "advisory lock"},
+		{query: &q.next, generate: db.sequenceNext, name: "next value fetching"},
+		{query: &q.nextN, generate: db.sequenceNextN, name: "next N values fetching"},
+		{query: &q.last, generate: db.sequenceLast, name: "last value fetching"},
+		{query: &q.forward, generate: db.sequenceForward, name: "forward value"},
+	} {
+		var err error
+
+		*g.query, err = g.generate()
+		if err != nil {
+			return nil, fmt.Errorf("generate the %s query: %w", g.name, err)
+		}
+	}
+
+	chain.queries[key] = q
+
+	return q, nil
+}
+
+// retry calls the function and if the sequence does not exist,
+// creates the sequence and calls the function once again.
+func (chain *PgxSequence) retry(ctx context.Context, q *pgxSequenceQueries, f func(*pgxpool.Conn) error) error {
+	conn, err := chain.conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	err = f(conn)
+	if !isUndefinedTable(err) {
+		return err
+	}
+
+	_, err = conn.Exec(ctx, q.create)
+	if err != nil && !isDuplicateSequence(err) {
+		return fmt.Errorf("create sequence: %w", err)
+	}
+
+	return f(conn)
+}
+
+// locked calls the function in the transaction holding the exclusive
+// advisory lock keyed by the sequence name, which blocks the concurrent
+// calls of the next method holding the shared advisory lock
+// until the end of the transaction, so the setval function
+// never moves the sequence backward and the catalog is not written.
+func (chain *PgxSequence) locked(ctx context.Context, conn *pgxpool.Conn, q *pgxSequenceQueries, f func(pgx.Tx) error) error {
+	return pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, q.lock, q.key)
+		if err != nil {
+			return err
+		}
+		return f(tx)
+	})
+}
+
+func (chain *PgxSequence) conn(ctx context.Context) (*pgxpool.Conn, error) {
+	conn, err := chain.pool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("acquire connection: %w", err)
+	}
+	return conn, nil
+}
+
+// Durable reports the chain is durable, the values are stored
+// in the sequences.
+// The durable method is thread safe.
+func (*PgxSequence) Durable() bool {
+	return true
+}
+
+// Health checks the connectivity by the ping.
+// The health method is thread safe.
+func (chain *PgxSequence) Health(ctx context.Context) error {
+	conn, err := chain.conn(ctx)
+	if err != nil {
+		return fmt.Errorf("check health: %w", err)
+	}
+	defer conn.Release()
+
+	err = conn.Ping(ctx)
+	if err != nil {
+		return fmt.Errorf("check health: %w", err)
+	}
+
+	return nil
+}
+
+// Close closes pgx pool.
+// The close method is thread safe.
+func (chain *PgxSequence) Close() error {
+	chain.Lock()
+	defer chain.Unlock()
+
+	if chain.closed {
+		return nil
+	}
+
+	chain.pool.Close()
+	chain.closed = true
+
+	return nil
+}
+
+// isUndefinedTable reports whether the error is the undefined table error
+// returned for the missing sequence.
+func isUndefinedTable(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "42P01"
+}
+
+// isDuplicateSequence reports whether the error is returned
+// by the concurrent creation of the same sequence.
+func isDuplicateSequence(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && (pgErr.Code == "42P07" || pgErr.Code == "23505")
+}
+
+// PgxSequenceOption changes configuration.
+type PgxSequenceOption func(*PgxSequenceConfiguration)
+
+// PgxSequenceConfiguration holds values changeable by options.
+type PgxSequenceConfiguration struct {
+	start      int64
+	prefix     string
+	increment  int64
+	increments map[string]int64
+}
+
+// PgxSequenceWithStart sets the start number of the created sequences.
+func PgxSequenceWithStart(start int64) PgxSequenceOption {
+	return func(cfg *PgxSequenceConfiguration) { cfg.start = start }
+}
+
+// PgxSequenceWithPrefix sets the prefix of the sequence names,
+// the prefix is sanitized as the key names are.
+func PgxSequenceWithPrefix(prefix string) PgxSequenceOption {
+	return func(cfg *PgxSequenceConfiguration) { cfg.prefix = prefix }
+}
+
+// PgxSequenceWithIncrement sets the positive increment of the sequences.
+func PgxSequenceWithIncrement(increment int64) PgxSequenceOption {
+	return func(cfg *PgxSequenceConfiguration) { cfg.increment = increment }
+}
+
+// PgxSequenceWithKeyIncrement sets the positive increment
+// of the sequence of the key name.
+func PgxSequenceWithKeyIncrement(key string, increment int64) PgxSequenceOption {
+	return func(cfg *PgxSequenceConfiguration) { cfg.increments[key] = increment }
+}
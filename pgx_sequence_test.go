@@ -0,0 +1,303 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package serialkey_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgproto3"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pfmt/serialkey"
+	"github.com/pfmt/serialkey/internal/serialkeytest"
+)
+
+var pgxSequenceOpt = serialkey.PgxSequenceWithStart(1)
+
+func TestPgxSequence(t *testing.T) {
+	if pgxErr != nil {
+		t.Log(pgxErr)
+		return
+	}
+
+	chain := serialkey.NewPgxSequence(pgxPool, pgxSequenceOpt)
+	serialkeytest.Next(t, chain)
+	serialkeytest.Forward(t, chain)
+	closer.add(chain.Close)
+}
+
+func BenchmarkPgxSequenceNext(b *testing.B) {
+	if pgxErr != nil {
+		b.Log(pgxErr)
+		return
+	}
+
+	chain := serialkey.NewPgxSequence(pgxPool, pgxSequenceOpt)
+	serialkeytest.BenchmarkNext(b, chain)
+	closer.add(chain.Close)
+}
+
+func TestPgxSequenceForward(t *testing.T) {
+	if pgxErr != nil {
+		t.Log(pgxErr)
+		return
+	}
+
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	chain := serialkey.NewPgxSequence(
+		pgxPool,
+		pgxSequenceOpt,
+		serialkey.PgxSequenceWithPrefix("serialkeys_forward_"),
+		serialkey.PgxSequenceWithKeyIncrement("bar", 10),
+	)
+	closer.add(chain.Close)
+
+	for _, key := range []string{"foo", "bar"} {
+		_, err := pgxPool.Exec(ctx, "DROP SEQUENCE IF EXISTS "+chain.Sequence(key))
+		if err != nil {
+			t.Fatalf("drop sequence: %s", err)
+		}
+	}
+
+	for _, tt := range []struct {
+		name string
+		f    func() (int64, error)
+		want int64
+	}{
+		{name: "foo last", f: func() (int64, error) { return chain.Last(ctx, "foo") }, want: 0},
+		{name: "foo next", f: func() (int64, error) { return chain.Next(ctx, "foo") }, want: 1},
+		{name: "foo forward", f: func() (int64, error) { return chain.Forward(ctx, "foo", 42) }, want: 42},
+		{name: "foo forward back", f: func() (int64, error) { return chain.Forward(ctx, "foo", 10) }, want: 43},
+		{name: "foo next n", f: func() (int64, error) { return chain.NextN(ctx, "foo", 10) }, want: 53},
+		{name: "foo last", f: func() (int64, error) { return chain.Last(ctx, "foo") }, want: 53},
+		{name: "bar next", f: func() (int64, error) { return chain.Next(ctx, "bar") }, want: 1},
+		{name: "bar next", f: func() (int64, error) { return chain.Next(ctx, "bar") }, want: 11},
+		{name: "bar next n", f: func() (int64, error) { return chain.NextN(ctx, "bar", 3) }, want: 41},
+		{name: "bar forward", f: func() (int64, error) { return chain.Forward(ctx, "bar", 100) }, want: 100},
+		{name: "bar next", f: func() (int64, error) { return chain.Next(ctx, "bar") }, want: 110},
+	} {
+		value, err := tt.f()
+		if err != nil {
+			t.Fatalf("%s: %s", tt.name, err)
+		}
+		if value != tt.want {
+			t.Errorf("want %s value: %d, got: %d", tt.name, tt.want, value)
+		}
+	}
+}
+
+func TestPgxSequenceName(t *testing.T) {
+	t.Parallel()
+
+	chain := serialkey.NewPgxSequence(nil)
+
+	tests := []struct {
+		name string
+		line string
+		opts []serialkey.PgxSequenceOption
+		key  string
+		want string
+	}{
+		{
+			name: "plain key",
+			line: testline(),
+			key:  "foo_42",
+			want: "serialkeys_seq_foo_42",
+		},
+		{
+			name: "upper case key",
+			line: testline(),
+			key:  "Foo",
+			want: "serialkeys_seq_foo_",
+		},
+		{
+			name: "quoted key",
+			line: testline(),
+			key:  `foo"; DROP TABLE serialkeys; --`,
+			want: "serialkeys_seq_foo___drop_table_serialkeys_____",
+		},
+		{
+			name: "long key",
+			line: testline(),
+			key:  strings.Repeat("x", 100),
+			want: "serialkeys_seq_" + strings.Repeat("x", 39) + "_",
+		},
+		{
+			name: "table named key",
+			line: testline(),
+			key:  "leases",
+			want: "serialkeys_seq_leases",
+		},
+		{
+			name: "quoted prefix",
+			line: testline(),
+			opts: []serialkey.PgxSequenceOption{serialkey.PgxSequenceWithPrefix(`Seq"; DROP TABLE serialkeys; --`)},
+			key:  "foo",
+			want: "seq___drop_table_serialkeys____foo_",
+		},
+		{
+			name: "digit leading name",
+			line: testline(),
+			opts: []serialkey.PgxSequenceOption{serialkey.PgxSequenceWithPrefix("")},
+			key:  "42",
+			want: "_42_",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.line+"/"+tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := serialkey.NewPgxSequence(nil, tt.opts...).Sequence(tt.key)
+
+			if !strings.HasPrefix(got, tt.want) {
+				t.Errorf("want sequence name prefix: %q, got: %q", tt.want, got)
+			}
+			if len(got) > serialkey.MaxSequenceLength {
+				t.Errorf("want sequence name length <= %d, got: %d", serialkey.MaxSequenceLength, len(got))
+			}
+			for _, r := range got {
+				if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_') {
+					t.Errorf("unexpected sequence name character: %q in %q", r, got)
+					break
+				}
+			}
+		})
+	}
+
+	if chain.Sequence("Foo") == chain.Sequence("foo") {
+		t.Errorf("want distinct sequence names of the distinct keys, got: %q", chain.Sequence("foo"))
+	}
+	if chain.Sequence("a-b") == chain.Sequence("a_b") {
+		t.Errorf("want distinct sequence names of the distinct keys, got: %q", chain.Sequence("a_b"))
+	}
+}
+
+func TestPgxSequenceLock(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		line  string
+		call  func(context.Context, *serialkey.PgxSequence) (int64, error)
+		want  string
+		value int64
+	}{
+		{
+			name:  "next",
+			line:  testline(),
+			call:  func(ctx context.Context, chain *serialkey.PgxSequence) (int64, error) { return chain.Next(ctx, "foo") },
+			want:  "pg_advisory_xact_lock_shared",
+			value: 42,
+		},
+		{
+			name: "next n",
+			line: testline(),
+			call: func(ctx context.Context, chain *serialkey.PgxSequence) (int64, error) {
+				return chain.NextN(ctx, "foo", 10)
+			},
+			want:  "pg_advisory_xact_lock(",
+			value: 42,
+		},
+		{
+			name: "forward",
+			line: testline(),
+			call: func(ctx context.Context, chain *serialkey.PgxSequence) (int64, error) {
+				return chain.Forward(ctx, "foo", 42)
+			},
+			want:  "pg_advisory_xact_lock(",
+			value: 42,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.line+"/"+tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			var (
+				mu      sync.Mutex
+				queries []string
+			)
+
+			url := newFakeServer(t, func(backend *pgproto3.Backend, query string) bool {
+				mu.Lock()
+				queries = append(queries, query)
+				mu.Unlock()
+
+				if strings.Contains(query, "nextval") || strings.Contains(query, "setval") {
+					sendValue(backend, tt.value)
+				} else {
+					backend.Send(&pgproto3.CommandComplete{CommandTag: []byte("SELECT 1")})
+				}
+
+				return true
+			})
+
+			pool, err := pgxpool.New(ctx, url)
+			if err != nil {
+				t.Fatalf("pgx connect %s: %s", url, err)
+			}
+
+			chain := serialkey.NewPgxSequence(pool, pgxSequenceOpt)
+			t.Cleanup(func() { _ = chain.Close() })
+
+			value, err := tt.call(ctx, chain)
+			if err != nil {
+				t.Fatalf("call: %s", err)
+			}
+			if value != tt.value {
+				t.Errorf("want value: %d, got: %d", tt.value, value)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			var locked bool
+
+			for _, q := range queries {
+				if strings.Contains(q, "ALTER SEQUENCE") {
+					t.Errorf("want no catalog writes, got query: %s", q)
+				}
+				if strings.Contains(q, tt.want) {
+					locked = true
+				}
+			}
+
+			if !locked {
+				t.Errorf("want the %s lock, got queries: %q", tt.want, queries)
+			}
+		})
+	}
+}
+
+func TestPgxSequenceInvalidCount(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	chain := serialkey.NewPgxSequence(nil, pgxSequenceOpt)
+
+	for _, count := range []int64{0, -1} {
+		_, err := chain.NextN(ctx, "foo", count)
+		if !errors.Is(err, serialkey.ErrInvalidRequest) {
+			t.Errorf("want invalid request error for count %d, got: %v", count, err)
+		}
+	}
+}
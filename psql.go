@@ -6,7 +6,7 @@ package serialkey
 
 import (
 	"bytes"
-	_ "embed"
+	"embed"
 	"fmt"
 	"html/template"
 )
@@ -39,13 +39,184 @@ func (db PostgreSQL) forward() (string, error) {
 	return db.generate(string(postgreSQLForward))
 }
 
-//go:embed psql_create_table.sql
+//go:embed psql_migrations/0001_create_table.sql
 var PostgreSQLCreateTable []byte
 
 func (db PostgreSQL) createTable() (string, error) {
 	return db.generate(string(PostgreSQLCreateTable))
 }
 
+//go:embed psql_drop_table.sql
+var postgreSQLDropTable []byte
+
+func (db PostgreSQL) dropTable() (string, error) {
+	return db.generate(string(postgreSQLDropTable))
+}
+
+//go:embed psql_list.sql
+var postgreSQLList []byte
+
+func (db PostgreSQL) list() (string, error) {
+	return db.generate(string(postgreSQLList))
+}
+
+//go:embed psql_reset.sql
+var postgreSQLReset []byte
+
+func (db PostgreSQL) reset() (string, error) {
+	return db.generate(string(postgreSQLReset))
+}
+
+//go:embed psql_columns.sql
+var postgreSQLColumns []byte
+
+func (db PostgreSQL) columns() (string, error) {
+	return db.generate(string(postgreSQLColumns))
+}
+
+//go:embed psql_migrations/0002_create_lease_table.sql
+var PostgreSQLCreateLeaseTable []byte
+
+func (db PostgreSQL) createLeaseTable() (string, error) {
+	return db.generate(string(PostgreSQLCreateLeaseTable))
+}
+
+//go:embed psql_lease_acquire.sql
+var postgreSQLLeaseAcquire []byte
+
+func (db PostgreSQL) leaseAcquire() (string, error) {
+	return db.generate(string(postgreSQLLeaseAcquire))
+}
+
+//go:embed psql_lease_renew.sql
+var postgreSQLLeaseRenew []byte
+
+func (db PostgreSQL) leaseRenew() (string, error) {
+	return db.generate(string(postgreSQLLeaseRenew))
+}
+
+//go:embed psql_lease_release.sql
+var postgreSQLLeaseRelease []byte
+
+func (db PostgreSQL) leaseRelease() (string, error) {
+	return db.generate(string(postgreSQLLeaseRelease))
+}
+
+//go:embed psql_sequence_create.sql
+var postgreSQLSequenceCreate []byte
+
+func (db PostgreSQL) sequenceCreate() (string, error) {
+	return db.generate(string(postgreSQLSequenceCreate))
+}
+
+//go:embed psql_sequence_next.sql
+var postgreSQLSequenceNext []byte
+
+func (db PostgreSQL) sequenceNext() (string, error) {
+	return db.generate(string(postgreSQLSequenceNext))
+}
+
+//go:embed psql_sequence_next_n.sql
+var postgreSQLSequenceNextN []byte
+
+func (db PostgreSQL) sequenceNextN() (string, error) {
+	return db.generate(string(postgreSQLSequenceNextN))
+}
+
+//go:embed psql_sequence_last.sql
+var postgreSQLSequenceLast []byte
+
+func (db PostgreSQL) sequenceLast() (string, error) {
+	return db.generate(string(postgreSQLSequenceLast))
+}
+
+//go:embed psql_sequence_forward.sql
+var postgreSQLSequenceForward []byte
+
+func (db PostgreSQL) sequenceForward() (string, error) {
+	return db.generate(string(postgreSQLSequenceForward))
+}
+
+//go:embed psql_schema_version_create.sql
+var postgreSQLSchemaVersionCreate []byte
+
+func (db PostgreSQL) schemaVersionCreate() (string, error) {
+	return db.generate(string(postgreSQLSchemaVersionCreate))
+}
+
+//go:embed psql_schema_version_exists.sql
+var postgreSQLSchemaVersionExists []byte
+
+func (db PostgreSQL) schemaVersionExists() (string, error) {
+	return db.generate(string(postgreSQLSchemaVersionExists))
+}
+
+//go:embed psql_schema_version_list.sql
+var postgreSQLSchemaVersionList []byte
+
+func (db PostgreSQL) schemaVersionList() (string, error) {
+	return db.generate(string(postgreSQLSchemaVersionList))
+}
+
+//go:embed psql_schema_version_insert.sql
+var postgreSQLSchemaVersionInsert []byte
+
+func (db PostgreSQL) schemaVersionInsert() (string, error) {
+	return db.generate(string(postgreSQLSchemaVersionInsert))
+}
+
+//go:embed psql_advisory_lock.sql
+var postgreSQLAdvisoryLock []byte
+
+func (db PostgreSQL) advisoryLock() (string, error) {
+	return db.generate(string(postgreSQLAdvisoryLock))
+}
+
+//go:embed psql_next_history.sql
+var postgreSQLNextHistory []byte
+
+func (db PostgreSQL) nextHistory() (string, error) {
+	return db.generate(string(postgreSQLNextHistory))
+}
+
+//go:embed psql_next_n_history.sql
+var postgreSQLNextNHistory []byte
+
+func (db PostgreSQL) nextNHistory() (string, error) {
+	return db.generate(string(postgreSQLNextNHistory))
+}
+
+//go:embed psql_forward_history.sql
+var postgreSQLForwardHistory []byte
+
+func (db PostgreSQL) forwardHistory() (string, error) {
+	return db.generate(string(postgreSQLForwardHistory))
+}
+
+//go:embed psql_reset_history.sql
+var postgreSQLResetHistory []byte
+
+func (db PostgreSQL) resetHistory() (string, error) {
+	return db.generate(string(postgreSQLResetHistory))
+}
+
+//go:embed psql_migrations/0003_create_history_table.sql
+var postgreSQLHistoryCreate []byte
+
+func (db PostgreSQL) historyCreate() (string, error) {
+	return db.generate(string(postgreSQLHistoryCreate))
+}
+
+//go:embed psql_history_list.sql
+var postgreSQLHistoryList []byte
+
+func (db PostgreSQL) historyList() (string, error) {
+	return db.generate(string(postgreSQLHistoryList))
+}
+
+//go:embed psql_migrations/*.sql
+var postgreSQLMigrations embed.FS
+
 func (db PostgreSQL) generate(query string) (string, error) {
 	tmpl, err := template.New("postgresql").Parse(query)
 	if err != nil {
@@ -63,5 +234,8 @@ func (db PostgreSQL) generate(query string) (string, error) {
 }
 
 type PostgreSQL struct {
-	Table string
+	Table     string
+	Sequence  string
+	Start     int64
+	Increment int64
 }
@@ -0,0 +1,6 @@
+SELECT a.attname, format_type(a.atttypid, a.atttypmod), a.attnotnull
+    FROM pg_attribute AS a
+    WHERE a.attrelid = to_regclass($1)
+        AND a.attnum > 0
+        AND NOT a.attisdropped
+    ORDER BY a.attnum;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS {{.Table}}_history;
+DROP TABLE IF EXISTS {{.Table}}_schema_version;
+DROP TABLE IF EXISTS {{.Table}}_leases;
+DROP TABLE IF EXISTS {{.Table}};
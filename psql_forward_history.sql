@@ -0,0 +1,15 @@
+WITH current AS (
+     SELECT value FROM {{.Table}} WHERE key = $1::text FOR UPDATE
+),   upsert AS (
+     INSERT INTO {{.Table}} (key, value)
+     SELECT $1::text, $2::bigint FROM (SELECT count(*) FROM current) AS locked
+     ON CONFLICT (key)
+     DO UPDATE SET
+        value = GREATEST({{.Table}}.value + 1, excluded.value),
+        updated_at = now()
+        RETURNING value, updated_at IS NULL AS inserted
+),   history AS (
+     INSERT INTO {{.Table}}_history (key, old_value, new_value, operation, actor)
+     SELECT $1::text, CASE WHEN inserted THEN NULL ELSE (SELECT value FROM current) END, value, 'forward', NULLIF($3::text, '')
+     FROM upsert
+) SELECT value FROM upsert;
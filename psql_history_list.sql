@@ -0,0 +1,3 @@
+SELECT key, old_value, new_value, operation, actor, created_at FROM {{.Table}}_history
+WHERE key = $1::text AND created_at >= $2::timestamptz
+ORDER BY created_at, id;
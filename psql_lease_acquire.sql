@@ -0,0 +1,22 @@
+WITH candidate AS (
+     SELECT series.worker FROM generate_series(0, $2::bigint - 1) AS series(worker)
+     WHERE NOT EXISTS (
+           SELECT 1 FROM {{.Table}}_leases AS lease
+           WHERE lease.key = $1::text
+             AND lease.worker = series.worker
+             AND lease.expires_at >= now()
+     )
+     ORDER BY series.worker
+     LIMIT 1
+), acquired AS (
+     INSERT INTO {{.Table}}_leases (key, worker, holder, expires_at)
+     SELECT $1::text, worker, $3::text, now() + $4::bigint * interval '1 millisecond' FROM candidate
+     ON CONFLICT (key, worker)
+     DO UPDATE SET
+        holder = excluded.holder,
+        expires_at = excluded.expires_at,
+        updated_at = now()
+        WHERE {{.Table}}_leases.expires_at < now()
+        RETURNING worker
+) SELECT (SELECT worker FROM acquired) AS worker,
+         EXISTS (SELECT 1 FROM candidate) AS available;
@@ -0,0 +1,4 @@
+DELETE FROM {{.Table}}_leases
+WHERE key = $1::text
+  AND worker = $2::bigint
+  AND holder = $3::text;
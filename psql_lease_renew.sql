@@ -0,0 +1,8 @@
+UPDATE {{.Table}}_leases
+SET expires_at = now() + $4::bigint * interval '1 millisecond',
+    updated_at = now()
+WHERE key = $1::text
+  AND worker = $2::bigint
+  AND holder = $3::text
+  AND expires_at >= now()
+  RETURNING worker;
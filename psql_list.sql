@@ -0,0 +1 @@
+SELECT key, value, created_at, updated_at FROM {{.Table}} ORDER BY key;
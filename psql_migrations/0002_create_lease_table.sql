@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS {{.Table}}_leases (
+    key text NOT NULL,
+    worker bigint NOT NULL,
+    holder text NOT NULL,
+    expires_at timestamp with time zone NOT NULL,
+    created_at timestamp with time zone NOT NULL DEFAULT now(),
+    updated_at timestamp with time zone,
+    PRIMARY KEY (key, worker)
+);
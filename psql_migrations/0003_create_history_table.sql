@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS {{.Table}}_history (
+    id bigserial primary key,
+    key text NOT NULL,
+    old_value bigint,
+    new_value bigint,
+    operation text NOT NULL,
+    actor text,
+    created_at timestamp with time zone NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS {{.Table}}_history_key_created_at_idx ON {{.Table}}_history (key, created_at);
@@ -0,0 +1,13 @@
+WITH upsert AS (
+     INSERT INTO {{.Table}} (key, value)
+     VALUES ($1::text, $2::bigint)
+     ON CONFLICT (key)
+     DO UPDATE SET
+        value = {{.Table}}.value + 1,
+        updated_at = now()
+        RETURNING value, updated_at IS NULL AS inserted
+),   history AS (
+     INSERT INTO {{.Table}}_history (key, old_value, new_value, operation, actor)
+     SELECT $1::text, CASE WHEN inserted THEN NULL ELSE value - 1 END, value, 'next', NULLIF($3::text, '')
+     FROM upsert
+) SELECT value FROM upsert;
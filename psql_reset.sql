@@ -0,0 +1 @@
+DELETE FROM {{.Table}} WHERE key = $1::text;
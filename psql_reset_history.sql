@@ -0,0 +1,4 @@
+WITH deleted AS (
+     DELETE FROM {{.Table}} WHERE key = $1::text RETURNING key, value
+) INSERT INTO {{.Table}}_history (key, old_value, new_value, operation, actor)
+  SELECT key, value, NULL, 'reset', NULLIF($2::text, '') FROM deleted;
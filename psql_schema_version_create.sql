@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS {{.Table}}_schema_version (
+    version bigint primary key,
+    name text NOT NULL,
+    applied_at timestamp with time zone NOT NULL DEFAULT now()
+);
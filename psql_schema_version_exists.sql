@@ -0,0 +1 @@
+SELECT to_regclass('{{.Table}}_schema_version') IS NOT NULL AS exists;
@@ -0,0 +1 @@
+INSERT INTO {{.Table}}_schema_version (version, name) VALUES ($1::bigint, $2::text);
@@ -0,0 +1 @@
+SELECT version, name, applied_at FROM {{.Table}}_schema_version ORDER BY version;
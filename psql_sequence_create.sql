@@ -0,0 +1,4 @@
+CREATE SEQUENCE IF NOT EXISTS "{{.Sequence}}" AS bigint
+    INCREMENT BY {{.Increment}}
+    MINVALUE {{.Start}}
+    START WITH {{.Start}};
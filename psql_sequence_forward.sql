@@ -0,0 +1,4 @@
+SELECT setval('"{{.Sequence}}"',
+       GREATEST(CASE WHEN is_called THEN last_value + {{.Increment}} ELSE last_value END,
+                $1::bigint))
+  FROM "{{.Sequence}}";
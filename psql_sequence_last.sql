@@ -0,0 +1 @@
+SELECT last_value, is_called FROM "{{.Sequence}}";
@@ -0,0 +1,2 @@
+SELECT nextval('"{{.Sequence}}"')
+  FROM (SELECT pg_advisory_xact_lock_shared($1::bigint)) AS locked;
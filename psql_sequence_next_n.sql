@@ -0,0 +1,4 @@
+SELECT setval('"{{.Sequence}}"',
+       (CASE WHEN is_called THEN last_value ELSE last_value - {{.Increment}} END)
+       + $1::bigint * {{.Increment}})
+  FROM "{{.Sequence}}";
@@ -0,0 +1,379 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package resp exposes the serialkey chain over the Redis protocol,
+// so the Redis clients may issue the values by the INCR, INCRBY, GET
+// and the custom FORWARD commands.
+package resp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pfmt/serialkey"
+)
+
+const (
+	// MaxArgs is the maximum number of the command arguments.
+	MaxArgs = 16
+	// MaxBulkLength is the maximum length of the command argument.
+	MaxBulkLength = 64 << 10
+)
+
+// New returns the Redis protocol server of the chain.
+func New(chain serialkey.Chain, opts ...Option) *Server {
+	cfg := Configuration{timeout: 10 * time.Second}
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return &Server{
+		chain:   chain,
+		timeout: cfg.timeout,
+		conns:   make(map[net.Conn]struct{}),
+	}
+}
+
+// Server is the Redis protocol server which maps the INCR command
+// to the next method, the INCRBY command to the next N method,
+// the GET command to the last method and the FORWARD command
+// to the forward method of the chain.
+type Server struct {
+	sync.Mutex
+	chain   serialkey.Chain
+	timeout time.Duration
+	conns   map[net.Conn]struct{}
+	wg      sync.WaitGroup
+}
+
+// ListenAndServe listens on the TCP network address and serves connections
+// until the context is done, then closes the connections and the chain.
+func (srv *Server) ListenAndServe(ctx context.Context, addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("listen %s: %w", addr, err)
+	}
+	return srv.Serve(ctx, ln)
+}
+
+// Serve serves connections on the listener until the context is done,
+// then closes the connections and the chain.
+func (srv *Server) Serve(ctx context.Context, ln net.Listener) error {
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-stop:
+		}
+		_ = ln.Close()
+	}()
+
+	var err error
+
+	for {
+		conn, e := ln.Accept()
+		if e != nil {
+			if ctx.Err() == nil {
+				err = fmt.Errorf("accept: %w", e)
+			}
+			break
+		}
+
+		srv.Lock()
+		srv.conns[conn] = struct{}{}
+		srv.Unlock()
+
+		srv.wg.Add(1)
+		go srv.serve(conn)
+	}
+
+	srv.Lock()
+	for conn := range srv.conns {
+		_ = conn.SetReadDeadline(time.Now())
+	}
+	srv.Unlock()
+
+	srv.wg.Wait()
+
+	if e := srv.chain.Close(); e != nil && err == nil {
+		err = fmt.Errorf("close chain: %w", e)
+	}
+
+	return err
+}
+
+func (srv *Server) serve(conn net.Conn) {
+	defer srv.wg.Done()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	defer func() {
+		srv.Lock()
+		delete(srv.conns, conn)
+		srv.Unlock()
+		_ = conn.Close()
+	}()
+
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			var perr protocolError
+			if errors.As(err, &perr) {
+				writeError(w, "ERR Protocol error: "+perr.Error())
+				_ = w.Flush()
+			}
+			return
+		}
+
+		if len(args) == 0 {
+			continue
+		}
+
+		quit := srv.exec(ctx, w, args)
+
+		if r.Buffered() == 0 || quit {
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+
+		if quit {
+			return
+		}
+	}
+}
+
+// exec executes the command and reports whether the connection must be closed.
+func (srv *Server) exec(ctx context.Context, w *bufio.Writer, args []string) bool {
+	name := strings.ToUpper(args[0])
+
+	switch name {
+	case "PING":
+		if len(args) > 2 {
+			writeArity(w, name)
+		} else if len(args) == 2 {
+			writeBulk(w, args[1])
+		} else {
+			writeSimple(w, "PONG")
+		}
+
+	case "ECHO":
+		if len(args) != 2 {
+			writeArity(w, name)
+		} else {
+			writeBulk(w, args[1])
+		}
+
+	case "QUIT":
+		writeSimple(w, "OK")
+		return true
+
+	case "SELECT", "CLIENT":
+		writeSimple(w, "OK")
+
+	case "INCR":
+		if len(args) != 2 {
+			writeArity(w, name)
+			break
+		}
+		srv.call(ctx, w, func(ctx context.Context) (int64, error) {
+			return srv.chain.Next(ctx, args[1])
+		}, writeInteger)
+
+	case "INCRBY":
+		if len(args) != 3 {
+			writeArity(w, name)
+			break
+		}
+		count, err := strconv.ParseInt(args[2], 10, 64)
+		if err != nil || count <= 0 {
+			writeError(w, "ERR value is not a positive integer or out of range")
+			break
+		}
+		chain, ok := srv.chain.(serialkey.NextNer)
+		if !ok {
+			writeError(w, "ERR INCRBY is not supported by the chain")
+			break
+		}
+		srv.call(ctx, w, func(ctx context.Context) (int64, error) {
+			return chain.NextN(ctx, args[1], count)
+		}, writeInteger)
+
+	case "GET":
+		if len(args) != 2 {
+			writeArity(w, name)
+			break
+		}
+		srv.call(ctx, w, func(ctx context.Context) (int64, error) {
+			return srv.chain.Last(ctx, args[1])
+		}, func(w *bufio.Writer, value int64) {
+			writeBulk(w, strconv.FormatInt(value, 10))
+		})
+
+	case "FORWARD":
+		if len(args) != 3 {
+			writeArity(w, name)
+			break
+		}
+		target, err := strconv.ParseInt(args[2], 10, 64)
+		if err != nil {
+			writeError(w, "ERR value is not an integer or out of range")
+			break
+		}
+		srv.call(ctx, w, func(ctx context.Context) (int64, error) {
+			return srv.chain.Forward(ctx, args[1], target)
+		}, writeInteger)
+
+	default:
+		writeError(w, fmt.Sprintf("ERR unknown command '%s'", args[0]))
+	}
+
+	return false
+}
+
+func (srv *Server) call(ctx context.Context, w *bufio.Writer, f func(context.Context) (int64, error), write func(*bufio.Writer, int64)) {
+	if srv.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, srv.timeout)
+		defer cancel()
+	}
+
+	value, err := f(ctx)
+	if err != nil {
+		writeError(w, "ERR "+errorReplacer.Replace(err.Error()))
+		return
+	}
+
+	write(w, value)
+}
+
+// errorReplacer replaces the line breaks of the error messages,
+// so the error reply is the single line.
+var errorReplacer = strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ")
+
+type protocolError string
+
+func (e protocolError) Error() string { return string(e) }
+
+// readCommand reads the array of the bulk strings or the inline command.
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(line) == 0 || line[0] != '*' {
+		return strings.Fields(line), nil
+	}
+
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n < 0 || n > MaxArgs {
+		return nil, protocolError("invalid multibulk length")
+	}
+
+	args := make([]string, 0, n)
+
+	for i := 0; i < n; i++ {
+		line, err := readLine(r)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(line) == 0 || line[0] != '$' {
+			return nil, protocolError(fmt.Sprintf("expected '$', got '%s'", line))
+		}
+
+		size, err := strconv.Atoi(line[1:])
+		if err != nil || size < 0 || size > MaxBulkLength {
+			return nil, protocolError("invalid bulk length")
+		}
+
+		buf := make([]byte, size+2)
+
+		_, err = io.ReadFull(r, buf)
+		if err != nil {
+			return nil, err
+		}
+
+		if buf[size] != '\r' || buf[size+1] != '\n' {
+			return nil, protocolError("invalid bulk terminator")
+		}
+
+		args = append(args, string(buf[:size]))
+	}
+
+	return args, nil
+}
+
+// readLine reads the line up to the maximum bulk length,
+// the longer line is rejected before it is read to the end.
+func readLine(r *bufio.Reader) (string, error) {
+	var line []byte
+
+	for {
+		chunk, err := r.ReadSlice('\n')
+
+		if len(line)+len(chunk) > MaxBulkLength {
+			return "", protocolError("too big inline request")
+		}
+
+		line = append(line, chunk...)
+
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		} else if err != nil {
+			return "", err
+		}
+
+		return strings.TrimRight(string(line), "\r\n"), nil
+	}
+}
+
+func writeSimple(w *bufio.Writer, s string) {
+	_, _ = w.WriteString("+" + s + "\r\n")
+}
+
+func writeError(w *bufio.Writer, s string) {
+	_, _ = w.WriteString("-" + s + "\r\n")
+}
+
+func writeInteger(w *bufio.Writer, i int64) {
+	_, _ = w.WriteString(":" + strconv.FormatInt(i, 10) + "\r\n")
+}
+
+func writeBulk(w *bufio.Writer, s string) {
+	_, _ = w.WriteString("$" + strconv.Itoa(len(s)) + "\r\n" + s + "\r\n")
+}
+
+func writeArity(w *bufio.Writer, name string) {
+	writeError(w, fmt.Sprintf("ERR wrong number of arguments for '%s' command", strings.ToLower(name)))
+}
+
+// Option changes configuration.
+type Option func(*Configuration)
+
+// Configuration holds values changeable by options.
+type Configuration struct {
+	timeout time.Duration
+}
+
+// WithTimeout sets the maximum duration of the chain call.
+func WithTimeout(timeout time.Duration) Option {
+	return func(cfg *Configuration) { cfg.timeout = timeout }
+}
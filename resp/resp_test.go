@@ -0,0 +1,238 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package resp_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pfmt/serialkey"
+	"github.com/pfmt/serialkey/resp"
+)
+
+var respTests = []struct {
+	test    string
+	line    string
+	request string
+	want    string
+}{
+	{
+		test:    "ping",
+		line:    testline(),
+		request: "*1\r\n$4\r\nPING\r\n",
+		want:    "+PONG\r\n",
+	}, {
+		test:    "inline ping",
+		line:    testline(),
+		request: "PING\r\n",
+		want:    "+PONG\r\n",
+	}, {
+		test:    "incr",
+		line:    testline(),
+		request: "*2\r\n$4\r\nINCR\r\n$3\r\nfoo\r\n",
+		want:    ":1\r\n",
+	}, {
+		test:    "pipelined incr",
+		line:    testline(),
+		request: "*2\r\n$4\r\nINCR\r\n$3\r\nbar\r\n*2\r\n$4\r\nincr\r\n$3\r\nbar\r\n",
+		want:    ":1\r\n:2\r\n",
+	}, {
+		test:    "incrby",
+		line:    testline(),
+		request: "*3\r\n$6\r\nINCRBY\r\n$3\r\nxyz\r\n$2\r\n42\r\n",
+		want:    ":42\r\n",
+	}, {
+		test:    "get",
+		line:    testline(),
+		request: "*2\r\n$3\r\nGET\r\n$3\r\nabc\r\n",
+		want:    "$1\r\n0\r\n",
+	}, {
+		test:    "forward",
+		line:    testline(),
+		request: "*3\r\n$7\r\nFORWARD\r\n$3\r\nqwe\r\n$4\r\n1000\r\n",
+		want:    ":1000\r\n",
+	}, {
+		test:    "wrong number of arguments",
+		line:    testline(),
+		request: "*1\r\n$4\r\nINCR\r\n",
+		want:    "-ERR wrong number of arguments for 'incr' command\r\n",
+	}, {
+		test:    "invalid increment",
+		line:    testline(),
+		request: "*3\r\n$6\r\nINCRBY\r\n$3\r\nxyz\r\n$3\r\nabc\r\n",
+		want:    "-ERR value is not a positive integer or out of range\r\n",
+	}, {
+		test:    "unknown command",
+		line:    testline(),
+		request: "*2\r\n$3\r\nDEL\r\n$3\r\nfoo\r\n",
+		want:    "-ERR unknown command 'DEL'\r\n",
+	}, {
+		test:    "too big inline request without line break",
+		line:    testline(),
+		request: strings.Repeat("a", resp.MaxBulkLength+4096),
+		want:    "-ERR Protocol error: too big inline request\r\n",
+	},
+}
+
+func TestServer(t *testing.T) {
+	t.Parallel()
+
+	addr := serve(t, serialkey.NewLocal(serialkey.LocalWithStart(1)))
+
+	for _, tt := range respTests {
+		tt := tt
+
+		t.Run(tt.line+"/"+tt.test, func(t *testing.T) {
+			t.Parallel()
+
+			conn, err := net.Dial("tcp", addr)
+			if err != nil {
+				t.Fatalf("dial: %s", err)
+			}
+			defer conn.Close()
+
+			_ = conn.SetDeadline(time.Now().Add(3 * time.Second))
+
+			_, err = io.WriteString(conn, tt.request)
+			if err != nil {
+				t.Fatalf("write: %s", err)
+			}
+
+			got := make([]byte, len(tt.want))
+
+			_, err = io.ReadFull(bufio.NewReader(conn), got)
+			if err != nil {
+				t.Fatalf("read: %s", err)
+			}
+
+			if string(got) != tt.want {
+				t.Errorf("\nwant: %q\ngot:  %q", tt.want, got)
+			}
+		})
+	}
+}
+
+type failing struct{ serialkey.Chain }
+
+func (failing) Next(context.Context, string) (int64, error) {
+	return 0, errors.New("foo\rbar\nbaz\r\nqux")
+}
+
+func TestServerErrorLineBreaks(t *testing.T) {
+	t.Parallel()
+
+	addr := serve(t, failing{Chain: serialkey.NewLocal()})
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("dial: %s", err)
+	}
+	defer conn.Close()
+
+	_ = conn.SetDeadline(time.Now().Add(3 * time.Second))
+
+	_, err = io.WriteString(conn, "*2\r\n$4\r\nINCR\r\n$3\r\nfoo\r\n")
+	if err != nil {
+		t.Fatalf("write: %s", err)
+	}
+
+	got, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		t.Fatalf("read: %s", err)
+	}
+
+	want := "-ERR foo bar baz qux\r\n"
+	if got != want {
+		t.Errorf("\nwant: %q\ngot:  %q", want, got)
+	}
+}
+
+type closing struct {
+	serialkey.Chain
+	closed chan struct{}
+}
+
+func (c closing) Close() error {
+	close(c.closed)
+	return c.Chain.Close()
+}
+
+func TestServerShutdown(t *testing.T) {
+	t.Parallel()
+
+	chain := closing{Chain: serialkey.NewLocal(), closed: make(chan struct{})}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %s", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	errs := make(chan error, 1)
+	go func() { errs <- resp.New(chain).Serve(ctx, ln) }()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %s", err)
+	}
+	defer conn.Close()
+
+	cancel()
+
+	select {
+	case err := <-errs:
+		if err != nil {
+			t.Errorf("serve: %s", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("want graceful shutdown")
+	}
+
+	select {
+	case <-chain.closed:
+	default:
+		t.Error("want closed chain")
+	}
+}
+
+func serve(t *testing.T, chain serialkey.Chain) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %s", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = resp.New(chain).Serve(ctx, ln)
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	return ln.Addr().String()
+}
+
+func testline() string {
+	_, file, line, ok := runtime.Caller(1)
+	if ok {
+		return fmt.Sprintf("%s:%d", filepath.Base(file), line)
+	}
+	return "it was not possible to recover file and line number information about function invocations"
+}
@@ -0,0 +1,129 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package serialkey
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// DefaultRetryPolicy is the retry policy suitable for the most of the cases.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries:     5,
+	InitialBackoff: 10 * time.Millisecond,
+	MaxBackoff:     time.Second,
+	MaxElapsed:     5 * time.Second,
+	Jitter:         0.5,
+}
+
+// RetryPolicy is the policy of the retries of the calls
+// failed by the transient errors.
+//
+// Retrying the next method may skip values but never duplicates them:
+// the retried statement either was rolled back by the database
+// or was committed but the response was lost,
+// in the latter case the value issued by the lost statement is skipped.
+type RetryPolicy struct {
+	// MaxRetries is the maximum number of the retries,
+	// the zero disables retries.
+	MaxRetries int
+	// InitialBackoff is the delay before the first retry.
+	InitialBackoff time.Duration
+	// MaxBackoff is the maximum delay between the retries,
+	// the delay doubles on each of the subsequent retries up to the maximum.
+	MaxBackoff time.Duration
+	// MaxElapsed is the maximum duration of the call including the retries,
+	// the retries are bounded by the context deadline as well.
+	MaxElapsed time.Duration
+	// Jitter is the fraction of the delay randomly subtracted from the delay
+	// to spread the retries of the concurrent calls, from 0 to 1.
+	Jitter float64
+}
+
+// retry calls the function and calls the function once again
+// while the function returns the retryable error
+// and the policy allows the retry.
+func (p RetryPolicy) retry(ctx context.Context, f func() error) error {
+	start := time.Now()
+	backoff := p.InitialBackoff
+
+	for i := 0; ; i++ {
+		err := f()
+		if err == nil || i >= p.MaxRetries || !IsRetryable(err) {
+			return err
+		}
+
+		delay := backoff
+		if p.Jitter > 0 && delay > 0 {
+			delay -= time.Duration(rand.Float64() * p.Jitter * float64(delay))
+		}
+
+		if p.MaxElapsed > 0 && time.Since(start)+delay > p.MaxElapsed {
+			return err
+		}
+
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(delay).After(deadline) {
+			return err
+		}
+
+		t := time.NewTimer(delay)
+
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		case <-t.C:
+		}
+
+		backoff *= 2
+		if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+			backoff = p.MaxBackoff
+		}
+	}
+}
+
+// retryableCodes is the set of the SQLSTATE codes of the errors
+// of the statements which were not committed.
+var retryableCodes = map[string]struct{}{
+	"08000": {}, // connection_exception
+	"08001": {}, // sqlclient_unable_to_establish_sqlconnection
+	"08003": {}, // connection_does_not_exist
+	"08004": {}, // sqlserver_rejected_establishment_of_sqlconnection
+	"08006": {}, // connection_failure
+	"40001": {}, // serialization_failure
+	"40P01": {}, // deadlock_detected
+	"53300": {}, // too_many_connections
+	"57P01": {}, // admin_shutdown
+	"57P02": {}, // crash_shutdown
+	"57P03": {}, // cannot_connect_now
+}
+
+// IsRetryable reports whether the error is the transient error
+// of the database or the network, so the call may be retried safely.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		_, ok := retryableCodes[pgErr.Code]
+		return ok
+	}
+
+	if pgconn.SafeToRetry(err) {
+		return true
+	}
+
+	var netErr net.Error
+
+	return errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,226 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package serialkey_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pfmt/serialkey"
+)
+
+var retryPolicy = serialkey.RetryPolicy{
+	MaxRetries:     3,
+	InitialBackoff: time.Millisecond,
+	MaxBackoff:     10 * time.Millisecond,
+	MaxElapsed:     time.Second,
+	Jitter:         0.5,
+}
+
+var pgxRetryTests = []struct {
+	name     string
+	line     string
+	code     string
+	failures int32
+	policy   serialkey.RetryPolicy
+	want     int64
+	queries  int32
+	err      bool
+}{
+	{
+		name:     "admin shutdown is retried",
+		line:     testline(),
+		code:     "57P01",
+		failures: 2,
+		policy:   retryPolicy,
+		want:     42,
+		queries:  3,
+	},
+	{
+		name:     "deadlock is retried",
+		line:     testline(),
+		code:     "40P01",
+		failures: 1,
+		policy:   retryPolicy,
+		want:     42,
+		queries:  2,
+	},
+	{
+		name:     "serialization failure is retried",
+		line:     testline(),
+		code:     "40001",
+		failures: 3,
+		policy:   retryPolicy,
+		want:     42,
+		queries:  4,
+	},
+	{
+		name:     "connection reset is retried",
+		line:     testline(),
+		failures: 2,
+		policy:   retryPolicy,
+		want:     42,
+		queries:  3,
+	},
+	{
+		name:     "unique violation is not retried",
+		line:     testline(),
+		code:     "23505",
+		failures: 1,
+		policy:   retryPolicy,
+		queries:  1,
+		err:      true,
+	},
+	{
+		name:     "retries are exhausted",
+		line:     testline(),
+		code:     "57P01",
+		failures: 10,
+		policy:   retryPolicy,
+		queries:  4,
+		err:      true,
+	},
+	{
+		name:     "zero policy does not retry",
+		line:     testline(),
+		code:     "57P01",
+		failures: 1,
+		queries:  1,
+		err:      true,
+	},
+	{
+		name:     "maximum elapsed time is exceeded",
+		line:     testline(),
+		code:     "57P01",
+		failures: 10,
+		policy: serialkey.RetryPolicy{
+			MaxRetries:     10,
+			InitialBackoff: 100 * time.Millisecond,
+			MaxElapsed:     150 * time.Millisecond,
+		},
+		queries: 2,
+		err:     true,
+	},
+}
+
+func TestPgxRetryPolicy(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range pgxRetryTests {
+		tt := tt
+
+		t.Run(tt.line+"/"+tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			var queries int32
+
+			url := newFaultCodeServer(t, tt.code, tt.failures, &queries)
+
+			pool, err := pgxpool.New(ctx, url)
+			if err != nil {
+				t.Fatalf("pgx connect %s: %s", url, err)
+			}
+
+			chain := serialkey.NewPgxPool(pool, pgxOpt, serialkey.PgxPoolWithRetryPolicy(tt.policy))
+			t.Cleanup(func() { _ = chain.Close() })
+
+			value, err := chain.Next(ctx, "foo")
+			if tt.err && err == nil {
+				t.Errorf("want error, got value: %d", value)
+			} else if !tt.err && err != nil {
+				t.Fatalf("next: %s", err)
+			}
+
+			if value != tt.want {
+				t.Errorf("want next value: %d, got: %d", tt.want, value)
+			}
+
+			if got := atomic.LoadInt32(&queries); got != tt.queries {
+				t.Errorf("want queries: %d, got: %d", tt.queries, got)
+			}
+		})
+	}
+}
+
+func TestPgxRetryPolicyDeadline(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	var queries int32
+
+	url := newFaultCodeServer(t, "57P01", 10, &queries)
+
+	pool, err := pgxpool.New(ctx, url)
+	if err != nil {
+		t.Fatalf("pgx connect %s: %s", url, err)
+	}
+
+	chain := serialkey.NewPgxPool(pool, pgxOpt, serialkey.PgxPoolWithRetryPolicy(serialkey.RetryPolicy{
+		MaxRetries:     10,
+		InitialBackoff: 150 * time.Millisecond,
+	}))
+	t.Cleanup(func() { _ = chain.Close() })
+
+	start := time.Now()
+
+	_, err = chain.Next(ctx, "foo")
+
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != "57P01" {
+		t.Errorf("want admin shutdown error, got: %v", err)
+	}
+
+	if got := atomic.LoadInt32(&queries); got != 2 {
+		t.Errorf("want queries within the deadline: 2, got: %d", got)
+	}
+
+	if elapsed := time.Since(start); elapsed > 200*time.Millisecond {
+		t.Errorf("want the retries bounded by the deadline, got elapsed: %s", elapsed)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		line string
+		err  error
+		want bool
+	}{
+		{name: "nil", line: testline(), err: nil, want: false},
+		{name: "serialization failure", line: testline(), err: &pgconn.PgError{Code: "40001"}, want: true},
+		{name: "wrapped admin shutdown", line: testline(), err: fmt.Errorf("next: %w", &pgconn.PgError{Code: "57P01"}), want: true},
+		{name: "unique violation", line: testline(), err: &pgconn.PgError{Code: "23505"}, want: false},
+		{name: "unexpected EOF", line: testline(), err: fmt.Errorf("read: %w", io.ErrUnexpectedEOF), want: true},
+		{name: "canceled", line: testline(), err: context.Canceled, want: false},
+		{name: "deadline exceeded", line: testline(), err: fmt.Errorf("next: %w", context.DeadlineExceeded), want: false},
+		{name: "other", line: testline(), err: errors.New("other"), want: false},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.line+"/"+tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := serialkey.IsRetryable(tt.err); got != tt.want {
+				t.Errorf("want retryable: %t, got: %t", tt.want, got)
+			}
+		})
+	}
+}
@@ -4,10 +4,28 @@
 
 package serialkey
 
-import "context"
+import (
+	"context"
+	"errors"
+)
 
 const Table = "serialkeys"
 
+// TracerName is the name of the OpenTelemetry tracer of the chains.
+const TracerName = "github.com/pfmt/serialkey"
+
+var (
+	// ErrInvalidRequest is returned when the request
+	// to the chain is malformed.
+	ErrInvalidRequest = errors.New("invalid request")
+	// ErrNotImplemented is returned when the chain
+	// does not support the method.
+	ErrNotImplemented = errors.New("not implemented")
+	// ErrUnavailable is returned when the chain rejects the call
+	// without calling the backend to protect the unhealthy backend.
+	ErrUnavailable = errors.New("unavailable")
+)
+
 // Chain is the persistence interface for the serialkey sequences.
 type Chain interface {
 	// Next for the passed key name returns an value guaranteed to be greater
@@ -35,3 +53,64 @@ type Chain interface {
 	// Specific implementations may document their own behavior.
 	Close() (err error)
 }
+
+// NextNer is the persistence interface for the serialkey sequences
+// issuing the blocks of values.
+type NextNer interface {
+	// NextN for the passed key name reserves the count of values
+	// and returns the last one of them, the returned value is guaranteed
+	// to be greater than the returned value for the same key name
+	// passed at the time of the previous method call by the count at least.
+	// NextN method must be thread safe.
+	NextN(ctx context.Context, key string, count int64) (value int64, err error)
+}
+
+// Pinger is the interface of the chains checking the health of the backend.
+type Pinger interface {
+	// Health returns the error if the backend is not usable.
+	// Health method must be thread safe.
+	Health(ctx context.Context) error
+}
+
+// Health checks the health of the backend of the chain
+// if the chain is the pinger, the chains without the remote backend
+// like the local chain are always healthy.
+func Health(ctx context.Context, chain Chain) error {
+	if p, ok := chain.(Pinger); ok {
+		return p.Health(ctx)
+	}
+	return nil
+}
+
+// Durabler is the interface of the chains keeping the issued values
+// across the restarts of the host.
+type Durabler interface {
+	// Durable reports whether the values issued before the restart
+	// are never issued again.
+	// Durable method must be thread safe.
+	Durable() bool
+}
+
+// Durable reports whether the chain is the durabler keeping the issued values,
+// the chains without the persistent backend like the local chain
+// are not durable.
+func Durable(chain Chain) bool {
+	d, ok := chain.(Durabler)
+	return ok && d.Durable()
+}
+
+// BlockStater is the interface of the chains issuing the values
+// from the blocks of values cached in memory.
+type BlockStater interface {
+	// BlockStats returns the statistics of the block cache.
+	// BlockStats method must be thread safe.
+	BlockStats() BlockStats
+}
+
+// BlockStats is the statistics of the block cache.
+type BlockStats struct {
+	// Hits is the number of the calls served from the cached block.
+	Hits int64
+	// Misses is the number of the calls reserved the new block.
+	Misses int64
+}
@@ -0,0 +1,398 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package serialkeybolt provides the serialkey chain
+// based on the bbolt database.
+package serialkeybolt
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pfmt/serialkey"
+	bolt "go.etcd.io/bbolt"
+)
+
+// New returns the serialkeys keychain based on the bbolt database,
+// each key is the bucket entry holding the big-endian 64-bit integer.
+func New(db *bolt.DB, opts ...Option) *Chain {
+	cfg := Configuration{bucket: serialkey.Table}
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return &Chain{
+		start:  cfg.start,
+		bucket: []byte(cfg.bucket),
+		block:  cfg.block,
+		db:     db,
+		blocks: make(map[string]*boltBlock),
+	}
+}
+
+// Chain is the serialkeys keychain based on the bbolt database.
+// The mutex guards the map of the blocks only, the block of the key
+// is guarded by its own mutex held across the reservation, so the calls
+// for the same key wait for the single reservation and the reservations
+// of the distinct keys are batched into the single transaction.
+type Chain struct {
+	sync.Mutex
+	start  int64
+	bucket []byte
+	block  int64
+	db     *bolt.DB
+	blocks map[string]*boltBlock
+	stats  serialkey.BlockStats
+	closed bool
+}
+
+// boltBlock is the range of the values preallocated in the database
+// from the next to the limit inclusive.
+type boltBlock struct {
+	sync.Mutex
+	next     int64
+	limit    int64
+	reserved bool
+}
+
+// Next for the passed key name returns an value guaranteed to be greater
+// than the value returned for the same key name passed at the time
+// of previous call of the next method or the forward method.
+// The next method is thread safe.
+func (chain *Chain) Next(ctx context.Context, key string) (int64, error) {
+	value, err := chain.nextN(ctx, key, 1)
+	if err != nil {
+		return 0, fmt.Errorf("fetch next value %s: %w", key, err)
+	}
+	return value, nil
+}
+
+// NextN for the passed key name reserves the count of values
+// and returns the last one of them.
+// The concurrent calls are batched into the single transaction.
+// The next N method is thread safe.
+func (chain *Chain) NextN(ctx context.Context, key string, count int64) (int64, error) {
+	value, err := chain.nextN(ctx, key, count)
+	if err != nil {
+		return 0, fmt.Errorf("fetch next values %s: %w", key, err)
+	}
+	return value, nil
+}
+
+func (chain *Chain) nextN(_ context.Context, key string, count int64) (int64, error) {
+	if count <= 0 {
+		return 0, fmt.Errorf("count must be positive: %d: %w", count, serialkey.ErrInvalidRequest)
+	}
+
+	if chain.block <= 1 {
+		return chain.update(key, func(value int64, ok bool) (int64, int64) {
+			if !ok {
+				value = chain.start - 1
+			}
+			return value + count, value + count
+		})
+	}
+
+	b := chain.cached(key)
+
+	b.Lock()
+	defer b.Unlock()
+
+	if b.reserved && b.limit-b.next >= count-1 {
+		b.next += count
+		atomic.AddInt64(&chain.stats.Hits, 1)
+		return b.next - 1, nil
+	}
+
+	atomic.AddInt64(&chain.stats.Misses, 1)
+
+	size := chain.block
+	if count > size {
+		size = count
+	}
+
+	var first int64
+
+	_, err := chain.update(key, func(value int64, ok bool) (int64, int64) {
+		if !ok {
+			value = chain.start - 1
+		}
+		first = value + 1
+		return value + size, value + size
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	b.next, b.limit, b.reserved = first+count, first+size-1, true
+
+	return first + count - 1, nil
+}
+
+// Last for the passed key name returns the value returned for
+// the same key name passed at the time of previous call
+// of the next method or the forward method.
+// The last method is thread safe.
+func (chain *Chain) Last(_ context.Context, key string) (int64, error) {
+	if chain.block > 1 {
+		chain.Lock()
+		b, ok := chain.blocks[key]
+		chain.Unlock()
+
+		if ok {
+			b.Lock()
+			next, reserved := b.next, b.reserved
+			b.Unlock()
+
+			if reserved {
+				return next - 1, nil
+			}
+		}
+	}
+
+	value := chain.start - 1
+
+	err := chain.db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket(chain.bucket); b != nil {
+			if v := b.Get([]byte(key)); v != nil {
+				value = int64(binary.BigEndian.Uint64(v))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("fetch last value %s: %w", key, err)
+	}
+
+	return value, nil
+}
+
+// Forward for the passed key name returns an value guaranteed
+// to be greater or equal to the target value and guaranteed to be greater
+// than the value returned for the same key name passed at the time
+// of previous call of the forward method or the next method.
+// The concurrent calls are batched into the single transaction.
+// The forward method is thread safe.
+func (chain *Chain) Forward(_ context.Context, key string, target int64) (int64, error) {
+	if chain.block <= 1 {
+		value, err := chain.update(key, func(value int64, ok bool) (int64, int64) {
+			if ok && value >= target {
+				return value + 1, value + 1
+			}
+			return target, target
+		})
+		if err != nil {
+			return 0, fmt.Errorf("forward value %s to %d: %w", key, target, err)
+		}
+		return value, nil
+	}
+
+	b := chain.cached(key)
+
+	b.Lock()
+	defer b.Unlock()
+
+	if b.reserved {
+		value := b.next
+		if value < target {
+			value = target
+		}
+		if value <= b.limit {
+			b.next = value + 1
+			atomic.AddInt64(&chain.stats.Hits, 1)
+			return value, nil
+		}
+	}
+
+	atomic.AddInt64(&chain.stats.Misses, 1)
+
+	value, err := chain.update(key, func(value int64, ok bool) (int64, int64) {
+		if ok && value >= target {
+			return value + chain.block, value + 1
+		}
+		return target + chain.block - 1, target
+	})
+	if err != nil {
+		return 0, fmt.Errorf("forward value %s to %d: %w", key, target, err)
+	}
+
+	b.next, b.limit, b.reserved = value+1, value+chain.block-1, true
+
+	return value, nil
+}
+
+// BlockStats returns the statistics of the block cache.
+// The block stats method is thread safe.
+func (chain *Chain) BlockStats() serialkey.BlockStats {
+	return serialkey.BlockStats{
+		Hits:   atomic.LoadInt64(&chain.stats.Hits),
+		Misses: atomic.LoadInt64(&chain.stats.Misses),
+	}
+}
+
+// cached returns the block of the key creating the empty one if missing.
+func (chain *Chain) cached(key string) *boltBlock {
+	chain.Lock()
+	defer chain.Unlock()
+
+	b, ok := chain.blocks[key]
+	if !ok {
+		b = &boltBlock{}
+		chain.blocks[key] = b
+	}
+
+	return b
+}
+
+// update stores the value returned by the function for the stored value
+// in the batched transaction and returns the result of the function.
+func (chain *Chain) update(key string, f func(value int64, ok bool) (store, result int64)) (int64, error) {
+	var result int64
+
+	err := chain.db.Batch(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(chain.bucket)
+		if err != nil {
+			return fmt.Errorf("create bucket %s: %w", chain.bucket, err)
+		}
+
+		var value int64
+
+		v := b.Get([]byte(key))
+		if v != nil {
+			value = int64(binary.BigEndian.Uint64(v))
+		}
+
+		var store int64
+		store, result = f(value, v != nil)
+
+		buf := make([]byte, 8)
+		binary.BigEndian.PutUint64(buf, uint64(store))
+
+		return b.Put([]byte(key), buf)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return result, nil
+}
+
+// Durable reports the chain is durable, the values are stored
+// in the database file.
+// The durable method is thread safe.
+func (*Chain) Durable() bool {
+	return true
+}
+
+// errHealthRollback rolls back the dry-run transaction of the health check.
+var errHealthRollback = errors.New("health check rollback")
+
+// Health confirms the write permission by the dry-run put
+// in the rolled back transaction.
+// The health method is thread safe.
+func (chain *Chain) Health(_ context.Context) error {
+	err := chain.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(chain.bucket)
+		if err != nil {
+			return fmt.Errorf("create bucket: %w", err)
+		}
+
+		err = b.Put([]byte(serialkey.HealthKey), make([]byte, 8))
+		if err != nil {
+			return fmt.Errorf("dry-run put: %w", err)
+		}
+
+		return errHealthRollback
+	})
+	if err != nil && !errors.Is(err, errHealthRollback) {
+		return fmt.Errorf("check health: %w", err)
+	}
+	return nil
+}
+
+// Close stores the last issued values of the preallocated blocks,
+// so the unused values are not skipped, and closes the bbolt database.
+// The blocks are locked until the database is closed,
+// so no value is issued from the block after its last value is stored.
+// The close method is thread safe.
+func (chain *Chain) Close() error {
+	chain.Lock()
+	defer chain.Unlock()
+
+	if chain.closed {
+		return nil
+	}
+
+	chain.closed = true
+
+	reserved := make(map[string]*boltBlock, len(chain.blocks))
+
+	for key, block := range chain.blocks {
+		block.Lock()
+		defer block.Unlock()
+
+		if block.reserved {
+			reserved[key] = block
+			block.reserved = false
+		}
+	}
+
+	if len(reserved) != 0 {
+		err := chain.db.Update(func(tx *bolt.Tx) error {
+			b := tx.Bucket(chain.bucket)
+			if b == nil {
+				return nil
+			}
+
+			for key, block := range reserved {
+				buf := make([]byte, 8)
+				binary.BigEndian.PutUint64(buf, uint64(block.next-1))
+
+				if err := b.Put([]byte(key), buf); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			_ = chain.db.Close()
+			return fmt.Errorf("store last values: %w", err)
+		}
+	}
+
+	return chain.db.Close()
+}
+
+// Option changes configuration.
+type Option func(*Configuration)
+
+// Configuration holds values changeable by options.
+type Configuration struct {
+	start  int64
+	bucket string
+	block  int64
+}
+
+// WithStart sets the start number.
+func WithStart(start int64) Option {
+	return func(cfg *Configuration) { cfg.start = start }
+}
+
+// WithBucket sets the bucket name.
+func WithBucket(bucket string) Option {
+	return func(cfg *Configuration) { cfg.bucket = bucket }
+}
+
+// WithBlock sets the number of values preallocated per transaction
+// to amortize fsync, the preallocated values not issued
+// before the crash are skipped but never duplicated.
+func WithBlock(block int64) Option {
+	return func(cfg *Configuration) { cfg.block = block }
+}
@@ -0,0 +1,214 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package serialkeybolt_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pfmt/serialkey"
+	"github.com/pfmt/serialkey/internal/serialkeytest"
+	"github.com/pfmt/serialkey/serialkeybolt"
+	bolt "go.etcd.io/bbolt"
+)
+
+var boltOpt = serialkeybolt.WithStart(1)
+
+func newBolt(t testing.TB, path string, opts ...serialkeybolt.Option) *serialkeybolt.Chain {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{NoSync: true})
+	if err != nil {
+		t.Fatalf("open bolt: %s", err)
+	}
+	db.MaxBatchDelay = time.Millisecond
+	return serialkeybolt.New(db, opts...)
+}
+
+func TestBolt(t *testing.T) {
+	chain := newBolt(t, filepath.Join(t.TempDir(), "serialkeys.db"), boltOpt)
+	serialkeytest.Next(t, chain)
+	serialkeytest.Forward(t, chain)
+	t.Cleanup(func() { _ = chain.Close() })
+}
+
+func TestBoltBlock(t *testing.T) {
+	chain := newBolt(t, filepath.Join(t.TempDir(), "serialkeys.db"), boltOpt, serialkeybolt.WithBlock(100))
+	serialkeytest.Next(t, chain)
+	serialkeytest.Forward(t, chain)
+	t.Cleanup(func() { _ = chain.Close() })
+}
+
+func BenchmarkBoltNext(b *testing.B) {
+	chain := newBolt(b, filepath.Join(b.TempDir(), "serialkeys.db"), boltOpt)
+	serialkeytest.BenchmarkNext(b, chain)
+	b.Cleanup(func() { _ = chain.Close() })
+}
+
+func TestBoltReopen(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), serialkeytest.Timeout)
+	defer cancel()
+
+	path := filepath.Join(t.TempDir(), "serialkeys.db")
+
+	chain := newBolt(t, path, boltOpt, serialkeybolt.WithBlock(100))
+
+	value, err := chain.NextN(ctx, "foo", 10)
+	if err != nil {
+		t.Fatalf("next n: %s", err)
+	}
+	if value != 10 {
+		t.Errorf("want next n value: 10, got: %d", value)
+	}
+
+	value, err = chain.Forward(ctx, "foo", 42)
+	if err != nil {
+		t.Fatalf("forward: %s", err)
+	}
+	if value != 42 {
+		t.Errorf("want forwarded value: 42, got: %d", value)
+	}
+
+	err = chain.Close()
+	if err != nil {
+		t.Fatalf("close: %s", err)
+	}
+
+	chain = newBolt(t, path, boltOpt)
+	defer chain.Close()
+
+	value, err = chain.Last(ctx, "foo")
+	if err != nil {
+		t.Fatalf("last: %s", err)
+	}
+	if value != 42 {
+		t.Errorf("want last value after reopen: 42, got: %d", value)
+	}
+
+	value, err = chain.Next(ctx, "foo")
+	if err != nil {
+		t.Fatalf("next: %s", err)
+	}
+	if value != 43 {
+		t.Errorf("want next value after reopen: 43, got: %d", value)
+	}
+}
+
+func TestBoltBlockBatch(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), serialkeytest.Timeout)
+	defer cancel()
+
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "serialkeys.db"), 0o600, &bolt.Options{NoSync: true})
+	if err != nil {
+		t.Fatalf("open bolt: %s", err)
+	}
+
+	// The batch is committed only when both of the reservations join it,
+	// so the reservation of one key must not block the other key.
+	db.MaxBatchSize = 2
+	db.MaxBatchDelay = time.Hour
+
+	chain := serialkeybolt.New(db, boltOpt, serialkeybolt.WithBlock(100))
+
+	errs := make(chan error, 2)
+
+	for _, key := range []string{"foo", "bar"} {
+		key := key
+		go func() {
+			_, err := chain.Next(ctx, key)
+			errs <- err
+		}()
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-errs:
+			if err != nil {
+				t.Fatalf("next: %s", err)
+			}
+		case <-ctx.Done():
+			t.Fatal("want the reservations of the distinct keys batched into the single transaction")
+		}
+	}
+
+	err = chain.Close()
+	if err != nil {
+		t.Errorf("close: %s", err)
+	}
+}
+
+func TestBoltInvalidCount(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), serialkeytest.Timeout)
+	defer cancel()
+
+	for _, block := range []int64{0, 100} {
+		chain := newBolt(t, filepath.Join(t.TempDir(), "serialkeys.db"), boltOpt, serialkeybolt.WithBlock(block))
+		t.Cleanup(func() { _ = chain.Close() })
+
+		for _, count := range []int64{0, -1} {
+			_, err := chain.NextN(ctx, "foo", count)
+			if !errors.Is(err, serialkey.ErrInvalidRequest) {
+				t.Errorf("block %d: want invalid request error for count %d, got: %v", block, count, err)
+			}
+		}
+
+		value, err := chain.Next(ctx, "foo")
+		if err != nil {
+			t.Fatalf("next: %s", err)
+		}
+		if value != 1 {
+			t.Errorf("block %d: want next value not moved by the invalid counts: 1, got: %d", block, value)
+		}
+	}
+}
+
+func TestBoltHealth(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), serialkeytest.Timeout)
+	defer cancel()
+
+	path := filepath.Join(t.TempDir(), "serialkeys.db")
+
+	chain := newBolt(t, path, boltOpt)
+
+	err := chain.Health(ctx)
+	if err != nil {
+		t.Fatalf("health: %s", err)
+	}
+
+	err = chain.Close()
+	if err != nil {
+		t.Fatalf("close: %s", err)
+	}
+
+	db, err := bolt.Open(path, 0o600, &bolt.Options{ReadOnly: true})
+	if err != nil {
+		t.Fatalf("open read-only bolt: %s", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	err = db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket([]byte(serialkey.Table)) != nil {
+			t.Errorf("want the dry-run put rolled back")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("view: %s", err)
+	}
+
+	err = serialkeybolt.New(db).Health(ctx)
+	if err == nil {
+		t.Errorf("want health error of the read-only database")
+	}
+}
@@ -0,0 +1,378 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package serialkeyetcd provides the serialkey chain
+// based on the etcd transactions.
+package serialkeyetcd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pfmt/serialkey"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// Prefix is the default prefix of the etcd keys.
+const Prefix = serialkey.Table + "/"
+
+// New returns the serialkeys keychain based on the etcd client.
+func New(client *clientv3.Client, opts ...Option) *Chain {
+	cfg := Configuration{prefix: Prefix}
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return &Chain{
+		start:  cfg.start,
+		prefix: cfg.prefix,
+		block:  cfg.block,
+		client: client,
+		blocks: make(map[string]*etcdBlock),
+	}
+}
+
+// Chain is the serialkeys keychain based on the etcd compare-and-swap
+// transactions on the key revision.
+// The mutex guards the map of the blocks only, the block of the key
+// is guarded by its own mutex held across the lease, so the calls
+// for the same key wait for the single lease and the leases
+// of the distinct keys run concurrently.
+type Chain struct {
+	sync.Mutex
+	start  int64
+	prefix string
+	block  int64
+	client *clientv3.Client
+	blocks map[string]*etcdBlock
+	stats  serialkey.BlockStats
+	kvs    sync.Map
+	closed bool
+}
+
+// etcdBlock is the range of the values leased from etcd
+// from the next to the limit inclusive.
+type etcdBlock struct {
+	sync.Mutex
+	next   int64
+	limit  int64
+	leased bool
+}
+
+// Next for the passed key name returns an value guaranteed to be greater
+// than the value returned for the same key name passed at the time
+// of previous call of the next method or the forward method.
+// The next method is thread safe.
+func (chain *Chain) Next(ctx context.Context, key string) (int64, error) {
+	value, err := chain.nextN(ctx, key, 1)
+	if err != nil {
+		return 0, fmt.Errorf("fetch next value %s: %w", key, err)
+	}
+	return value, nil
+}
+
+// NextN for the passed key name reserves the count of values
+// and returns the last one of them.
+// The next N method is thread safe.
+func (chain *Chain) NextN(ctx context.Context, key string, count int64) (int64, error) {
+	value, err := chain.nextN(ctx, key, count)
+	if err != nil {
+		return 0, fmt.Errorf("fetch next values %s: %w", key, err)
+	}
+	return value, nil
+}
+
+func (chain *Chain) nextN(ctx context.Context, key string, count int64) (int64, error) {
+	if count <= 0 {
+		return 0, fmt.Errorf("count must be positive: %d: %w", count, serialkey.ErrInvalidRequest)
+	}
+
+	if chain.block <= 1 {
+		return chain.swap(ctx, key, func(value int64, ok bool) (int64, int64) {
+			if !ok {
+				value = chain.start - 1
+			}
+			return value + count, value + count
+		})
+	}
+
+	b := chain.cached(key)
+
+	b.Lock()
+	defer b.Unlock()
+
+	if b.leased && b.limit-b.next >= count-1 {
+		b.next += count
+		atomic.AddInt64(&chain.stats.Hits, 1)
+		return b.next - 1, nil
+	}
+
+	atomic.AddInt64(&chain.stats.Misses, 1)
+
+	size := chain.block
+	if count > size {
+		size = count
+	}
+
+	first, err := chain.swap(ctx, key, func(value int64, ok bool) (int64, int64) {
+		if !ok {
+			value = chain.start - 1
+		}
+		return value + size, value + 1
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	b.next, b.limit, b.leased = first+count, first+size-1, true
+
+	return first + count - 1, nil
+}
+
+// Last for the passed key name returns the value returned for
+// the same key name passed at the time of previous call
+// of the next method or the forward method,
+// the values leased by the other clients are counted as returned.
+// The last method is thread safe.
+func (chain *Chain) Last(ctx context.Context, key string) (int64, error) {
+	if chain.block > 1 {
+		chain.Lock()
+		b, ok := chain.blocks[key]
+		chain.Unlock()
+
+		if ok {
+			b.Lock()
+			next, leased := b.next, b.leased
+			b.Unlock()
+
+			if leased {
+				return next - 1, nil
+			}
+		}
+	}
+
+	resp, err := chain.client.Get(ctx, chain.prefix+key)
+	if err != nil {
+		return 0, fmt.Errorf("fetch last value %s: %w", key, err)
+	}
+
+	if len(resp.Kvs) == 0 {
+		return chain.start - 1, nil
+	}
+
+	value, err := strconv.ParseInt(string(resp.Kvs[0].Value), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse last value %s: %w", key, err)
+	}
+
+	return value, nil
+}
+
+// Forward for the passed key name returns an value guaranteed
+// to be greater or equal to the target value and guaranteed to be greater
+// than the value returned for the same key name passed at the time
+// of previous call of the forward method or the next method.
+// The forward method is thread safe.
+func (chain *Chain) Forward(ctx context.Context, key string, target int64) (int64, error) {
+	if chain.block <= 1 {
+		value, err := chain.swap(ctx, key, func(value int64, ok bool) (int64, int64) {
+			if ok && value >= target {
+				return value + 1, value + 1
+			}
+			return target, target
+		})
+		if err != nil {
+			return 0, fmt.Errorf("forward value %s to %d: %w", key, target, err)
+		}
+		return value, nil
+	}
+
+	b := chain.cached(key)
+
+	b.Lock()
+	defer b.Unlock()
+
+	if b.leased {
+		value := b.next
+		if value < target {
+			value = target
+		}
+		if value <= b.limit {
+			b.next = value + 1
+			atomic.AddInt64(&chain.stats.Hits, 1)
+			return value, nil
+		}
+	}
+
+	atomic.AddInt64(&chain.stats.Misses, 1)
+
+	value, err := chain.swap(ctx, key, func(value int64, ok bool) (int64, int64) {
+		if ok && value >= target {
+			return value + chain.block, value + 1
+		}
+		return target + chain.block - 1, target
+	})
+	if err != nil {
+		return 0, fmt.Errorf("forward value %s to %d: %w", key, target, err)
+	}
+
+	b.next, b.limit, b.leased = value+1, value+chain.block-1, true
+
+	return value, nil
+}
+
+// BlockStats returns the statistics of the block cache.
+// The block stats method is thread safe.
+func (chain *Chain) BlockStats() serialkey.BlockStats {
+	return serialkey.BlockStats{
+		Hits:   atomic.LoadInt64(&chain.stats.Hits),
+		Misses: atomic.LoadInt64(&chain.stats.Misses),
+	}
+}
+
+// cached returns the block of the key creating the empty one if missing.
+func (chain *Chain) cached(key string) *etcdBlock {
+	chain.Lock()
+	defer chain.Unlock()
+
+	b, ok := chain.blocks[key]
+	if !ok {
+		b = &etcdBlock{}
+		chain.blocks[key] = b
+	}
+
+	return b
+}
+
+// swap stores the value returned by the function for the stored value
+// by the compare-and-swap transaction on the key revision,
+// retries on the conflicts and returns the result of the function.
+// The last swapped value is cached, so the uncontended swap
+// takes the single round trip.
+func (chain *Chain) swap(ctx context.Context, key string, f func(value int64, ok bool) (store, result int64)) (int64, error) {
+	name := chain.prefix + key
+
+	var kvs []*mvccpb.KeyValue
+
+	if kv, ok := chain.kvs.Load(key); ok {
+		kvs = []*mvccpb.KeyValue{kv.(*mvccpb.KeyValue)}
+	} else {
+		resp, err := chain.client.Get(ctx, name)
+		if err != nil {
+			return 0, err
+		}
+		kvs = resp.Kvs
+	}
+
+	for {
+		var err error
+		var value, revision int64
+
+		if len(kvs) != 0 {
+			value, err = strconv.ParseInt(string(kvs[0].Value), 10, 64)
+			if err != nil {
+				return 0, fmt.Errorf("parse value %s: %w", key, err)
+			}
+			revision = kvs[0].ModRevision
+		}
+
+		store, result := f(value, len(kvs) != 0)
+
+		txn, err := chain.client.Txn(ctx).
+			If(clientv3.Compare(clientv3.ModRevision(name), "=", revision)).
+			Then(clientv3.OpPut(name, strconv.FormatInt(store, 10))).
+			Else(clientv3.OpGet(name)).
+			Commit()
+		if err != nil {
+			return 0, err
+		}
+
+		if txn.Succeeded {
+			chain.kvs.Store(key, &mvccpb.KeyValue{
+				Key:         []byte(name),
+				Value:       []byte(strconv.FormatInt(store, 10)),
+				ModRevision: txn.Header.Revision,
+			})
+			return result, nil
+		}
+
+		kvs = txn.Responses[0].GetResponseRange().Kvs
+	}
+}
+
+// Durable reports the chain is durable, the values are stored
+// in the etcd cluster.
+// The durable method is thread safe.
+func (*Chain) Durable() bool {
+	return true
+}
+
+// Health checks the connectivity and confirms the write permission
+// by the transaction which put is never applied.
+// The health method is thread safe.
+func (chain *Chain) Health(ctx context.Context) error {
+	name := chain.prefix + serialkey.HealthKey
+
+	// The permissions of the operations of the both branches are checked,
+	// but the revision is never negative, so the put is never applied.
+	_, err := chain.client.Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision(name), "<", 0)).
+		Then(clientv3.OpPut(name, "")).
+		Else(clientv3.OpGet(name, clientv3.WithCountOnly())).
+		Commit()
+	if err != nil {
+		return fmt.Errorf("check health: %w", err)
+	}
+	return nil
+}
+
+// Close closes the etcd client.
+// The unused values of the leased blocks are skipped, they are never given
+// back, because the other clients may have issued or forwarded past
+// the stored limit of the block, so the stored value never moves backwards.
+// The close method is thread safe.
+func (chain *Chain) Close() error {
+	chain.Lock()
+	defer chain.Unlock()
+
+	if chain.closed {
+		return nil
+	}
+
+	chain.closed = true
+
+	return chain.client.Close()
+}
+
+// Option changes configuration.
+type Option func(*Configuration)
+
+// Configuration holds values changeable by options.
+type Configuration struct {
+	start  int64
+	prefix string
+	block  int64
+}
+
+// WithStart sets the start number.
+func WithStart(start int64) Option {
+	return func(cfg *Configuration) { cfg.start = start }
+}
+
+// WithPrefix sets the prefix of the etcd keys.
+func WithPrefix(prefix string) Option {
+	return func(cfg *Configuration) { cfg.prefix = prefix }
+}
+
+// WithBlock sets the number of values leased per transaction
+// to keep the write volume reasonable, the leased values not issued
+// before the crash are skipped but never duplicated.
+func WithBlock(block int64) Option {
+	return func(cfg *Configuration) { cfg.block = block }
+}
@@ -0,0 +1,306 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package serialkeyetcd_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pfmt/serialkey"
+	"github.com/pfmt/serialkey/internal/serialkeytest"
+	"github.com/pfmt/serialkey/serialkeyetcd"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/server/v3/embed"
+)
+
+var etcdOpt = serialkeyetcd.WithStart(1)
+
+// newEtcdServer starts the embedded etcd server
+// and returns the client endpoint of the server.
+func newEtcdServer(t testing.TB) string {
+	cfg := embed.NewConfig()
+	cfg.Dir = t.TempDir()
+	cfg.LogLevel = "error"
+	cfg.UnsafeNoFsync = true
+
+	client, peer := freeURL(t), freeURL(t)
+	cfg.ListenClientUrls = []url.URL{client}
+	cfg.AdvertiseClientUrls = []url.URL{client}
+	cfg.ListenPeerUrls = []url.URL{peer}
+	cfg.AdvertisePeerUrls = []url.URL{peer}
+	cfg.InitialCluster = cfg.InitialClusterFromName(cfg.Name)
+
+	e, err := embed.StartEtcd(cfg)
+	if err != nil {
+		t.Fatalf("start etcd: %s", err)
+	}
+	t.Cleanup(e.Close)
+
+	select {
+	case <-e.Server.ReadyNotify():
+	case <-time.After(serialkeytest.Timeout):
+		t.Fatalf("start etcd: timeout")
+	}
+
+	return client.String()
+}
+
+func freeURL(t testing.TB) url.URL {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %s", err)
+	}
+	defer ln.Close()
+
+	return url.URL{Scheme: "http", Host: ln.Addr().String()}
+}
+
+func newEtcd(t testing.TB, endpoint string, opts ...serialkeyetcd.Option) *serialkeyetcd.Chain {
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   []string{endpoint},
+		DialTimeout: serialkeytest.Timeout,
+	})
+	if err != nil {
+		t.Fatalf("new etcd client: %s", err)
+	}
+	chain := serialkeyetcd.New(client, opts...)
+	t.Cleanup(func() { _ = chain.Close() })
+	return chain
+}
+
+func TestEtcd(t *testing.T) {
+	chain := newEtcd(t, newEtcdServer(t), etcdOpt)
+	serialkeytest.Next(t, chain)
+	serialkeytest.Forward(t, chain)
+}
+
+func TestEtcdBlock(t *testing.T) {
+	chain := newEtcd(t, newEtcdServer(t), etcdOpt, serialkeyetcd.WithBlock(100))
+	serialkeytest.Next(t, chain)
+	serialkeytest.Forward(t, chain)
+}
+
+func BenchmarkEtcdNext(b *testing.B) {
+	chain := newEtcd(b, newEtcdServer(b), etcdOpt)
+	serialkeytest.BenchmarkNext(b, chain)
+}
+
+func TestEtcdConcurrent(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), serialkeytest.Timeout)
+	defer cancel()
+
+	endpoint := newEtcdServer(t)
+
+	chains := []*serialkeyetcd.Chain{
+		newEtcd(t, endpoint, etcdOpt),
+		newEtcd(t, endpoint, etcdOpt),
+		newEtcd(t, endpoint, etcdOpt, serialkeyetcd.WithBlock(10)),
+		newEtcd(t, endpoint, etcdOpt, serialkeyetcd.WithBlock(10)),
+	}
+
+	var (
+		mu     sync.Mutex
+		values = make(map[int64]struct{})
+		wg     sync.WaitGroup
+	)
+
+	for i, chain := range chains {
+		wg.Add(1)
+		go func(i int, chain *serialkeyetcd.Chain) {
+			defer wg.Done()
+
+			for j := 0; j < 25; j++ {
+				value, err := chain.Next(ctx, "foo")
+				if err != nil {
+					t.Errorf("chain %d next: %s", i, err)
+					return
+				}
+
+				mu.Lock()
+				if _, ok := values[value]; ok {
+					t.Errorf("chain %d duplicated value: %d", i, value)
+				}
+				values[value] = struct{}{}
+				mu.Unlock()
+			}
+		}(i, chain)
+	}
+
+	wg.Wait()
+
+	if len(values) != 100 {
+		t.Errorf("want unique values: 100, got: %d", len(values))
+	}
+}
+
+func TestEtcdClose(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), serialkeytest.Timeout)
+	defer cancel()
+
+	endpoint := newEtcdServer(t)
+
+	chain := newEtcd(t, endpoint, etcdOpt, serialkeyetcd.WithBlock(100))
+
+	value, err := chain.NextN(ctx, "foo", 10)
+	if err != nil {
+		t.Fatalf("next n: %s", err)
+	}
+	if value != 10 {
+		t.Errorf("want next n value: 10, got: %d", value)
+	}
+
+	value, err = chain.Forward(ctx, "foo", 42)
+	if err != nil {
+		t.Fatalf("forward: %s", err)
+	}
+	if value != 42 {
+		t.Errorf("want forwarded value: 42, got: %d", value)
+	}
+
+	err = chain.Close()
+	if err != nil {
+		t.Fatalf("close: %s", err)
+	}
+
+	chain = newEtcd(t, endpoint, etcdOpt)
+
+	for _, tt := range []struct {
+		name string
+		f    func(context.Context, string) (int64, error)
+		want int64
+	}{
+		{name: "last", f: chain.Last, want: 100},
+		{name: "next", f: chain.Next, want: 101},
+	} {
+		value, err := tt.f(ctx, "foo")
+		if err != nil {
+			t.Fatalf("%s: %s", tt.name, err)
+		}
+		if value != tt.want {
+			t.Errorf("want %s value after close: %d, got: %d", tt.name, tt.want, value)
+		}
+	}
+}
+
+func TestEtcdCloseForwarded(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), serialkeytest.Timeout)
+	defer cancel()
+
+	endpoint := newEtcdServer(t)
+
+	a := newEtcd(t, endpoint, etcdOpt, serialkeyetcd.WithBlock(100))
+	b := newEtcd(t, endpoint, etcdOpt)
+
+	var max int64
+
+	next := func(name string, value int64, err error) {
+		t.Helper()
+
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		if value <= max {
+			t.Errorf("want %s value greater than %d, got: %d", name, max, value)
+		}
+		max = value
+	}
+
+	forward := func(target, value int64, err error) {
+		t.Helper()
+
+		if err != nil {
+			t.Fatalf("forward %d: %s", target, err)
+		}
+		if value < target || value < max {
+			t.Errorf("want forwarded value at least %d and %d, got: %d", target, max, value)
+		}
+		max = value
+	}
+
+	value, err := a.Next(ctx, "foo")
+	next("a next", value, err)
+
+	// The forward returns the stored limit of the block leased by a.
+	value, err = b.Forward(ctx, "foo", 50)
+	forward(50, value, err)
+
+	err = a.Close()
+	if err != nil {
+		t.Fatalf("close: %s", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		value, err = b.Next(ctx, "foo")
+		next("b next", value, err)
+	}
+
+	value, err = b.Forward(ctx, "foo", 99)
+	forward(99, value, err)
+
+	value, err = b.Next(ctx, "foo")
+	next("b next", value, err)
+}
+
+func TestEtcdHealth(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), serialkeytest.Timeout)
+	defer cancel()
+
+	chain := newEtcd(t, newEtcdServer(t), etcdOpt)
+
+	err := chain.Health(ctx)
+	if err != nil {
+		t.Fatalf("health: %s", err)
+	}
+
+	value, err := chain.Last(ctx, serialkey.HealthKey)
+	if err != nil {
+		t.Fatalf("last: %s", err)
+	}
+	if value != 0 {
+		t.Errorf("want the dry-run put never applied, got value: %d", value)
+	}
+}
+
+func TestEtcdInvalidCount(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), serialkeytest.Timeout)
+	defer cancel()
+
+	endpoint := newEtcdServer(t)
+
+	for i, block := range []int64{0, 100} {
+		chain := newEtcd(t, endpoint, etcdOpt, serialkeyetcd.WithPrefix(fmt.Sprintf("block%d/", i)), serialkeyetcd.WithBlock(block))
+
+		for _, count := range []int64{0, -1} {
+			_, err := chain.NextN(ctx, "foo", count)
+			if !errors.Is(err, serialkey.ErrInvalidRequest) {
+				t.Errorf("block %d: want invalid request error for count %d, got: %v", block, count, err)
+			}
+		}
+
+		value, err := chain.Next(ctx, "foo")
+		if err != nil {
+			t.Fatalf("next: %s", err)
+		}
+		if value != 1 {
+			t.Errorf("block %d: want next value not moved by the invalid counts: 1, got: %d", block, value)
+		}
+	}
+}
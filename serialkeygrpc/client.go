@@ -0,0 +1,135 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package serialkeygrpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	"google.golang.org/grpc"
+)
+
+// NewClient returns the serialkeys keychain based on the gRPC connection.
+func NewClient(conn *grpc.ClientConn) *Client {
+	return &Client{conn: conn, client: NewSerialKeyClient(conn)}
+}
+
+// Client is the serialkeys keychain based on the gRPC server.
+type Client struct {
+	sync.RWMutex
+	conn   *grpc.ClientConn
+	client SerialKeyClient
+	closed bool
+}
+
+// Next for the passed key name returns an value guaranteed to be greater
+// than the value returned for the same key name passed at the time
+// of previous call of the next method or the forward method.
+// The next method is thread safe.
+func (chain *Client) Next(ctx context.Context, key string) (int64, error) {
+	res, err := chain.client.Next(ctx, &NextRequest{Key: key})
+	if err != nil {
+		return 0, chainError("next", err)
+	}
+	return res.GetValue(), nil
+}
+
+// NextN for the passed key name reserves the count of values
+// and returns the last one of them.
+// The next N method is thread safe.
+func (chain *Client) NextN(ctx context.Context, key string, count int64) (int64, error) {
+	res, err := chain.client.NextN(ctx, &NextNRequest{Key: key, Count: count})
+	if err != nil {
+		return 0, chainError("next n", err)
+	}
+	return res.GetValue(), nil
+}
+
+// Last for the passed key name returns the value returned for
+// the same key name passed at the time of previous call
+// of the next method or the forward method.
+// The last method is thread safe.
+func (chain *Client) Last(ctx context.Context, key string) (int64, error) {
+	res, err := chain.client.Last(ctx, &LastRequest{Key: key})
+	if err != nil {
+		return 0, chainError("last", err)
+	}
+	return res.GetValue(), nil
+}
+
+// Forward for the passed key name returns an value guaranteed
+// to be greater or equal to the target value and guaranteed to be greater
+// than the value returned for the same key name passed at the time
+// of previous call of the forward method or the next method.
+// The forward method is thread safe.
+func (chain *Client) Forward(ctx context.Context, key string, target int64) (int64, error) {
+	res, err := chain.client.Forward(ctx, &ForwardRequest{Key: key, Target: target})
+	if err != nil {
+		return 0, chainError("forward", err)
+	}
+	return res.GetValue(), nil
+}
+
+// NextMany returns the next values of the several sequences
+// in the order of the passed key names.
+// The next many method is thread safe.
+func (chain *Client) NextMany(ctx context.Context, keys ...string) ([]int64, error) {
+	res, err := chain.client.NextMany(ctx, &NextManyRequest{Keys: keys})
+	if err != nil {
+		return nil, chainError("next many", err)
+	}
+
+	values := make([]int64, 0, len(res.GetValues()))
+	for _, v := range res.GetValues() {
+		values = append(values, v.GetValue())
+	}
+
+	return values, nil
+}
+
+// Reserve calls the function for each of the count of blocks
+// of the size values each streamed by the server,
+// the block holds the values from the first to the last inclusive.
+// The reserve method is thread safe.
+func (chain *Client) Reserve(ctx context.Context, key string, size, count int64, f func(first, last int64) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := chain.client.Reserve(ctx, &ReserveRequest{Key: key, Size: size, Count: count})
+	if err != nil {
+		return chainError("reserve", err)
+	}
+
+	for {
+		block, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return chainError("reserve", err)
+		}
+
+		err = f(block.GetFirst(), block.GetLast())
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Close closes the gRPC connection.
+// The close method is thread safe.
+func (chain *Client) Close() error {
+	chain.Lock()
+	defer chain.Unlock()
+
+	if chain.closed {
+		return nil
+	}
+
+	chain.closed = true
+
+	return chain.conn.Close()
+}
@@ -0,0 +1,338 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: serialkey.proto
+
+package serialkeygrpc
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	SerialKey_Next_FullMethodName     = "/serialkey.v1.SerialKey/Next"
+	SerialKey_NextN_FullMethodName    = "/serialkey.v1.SerialKey/NextN"
+	SerialKey_Last_FullMethodName     = "/serialkey.v1.SerialKey/Last"
+	SerialKey_Forward_FullMethodName  = "/serialkey.v1.SerialKey/Forward"
+	SerialKey_NextMany_FullMethodName = "/serialkey.v1.SerialKey/NextMany"
+	SerialKey_Reserve_FullMethodName  = "/serialkey.v1.SerialKey/Reserve"
+)
+
+// SerialKeyClient is the client API for SerialKey service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type SerialKeyClient interface {
+	// Next returns the next value of the sequence.
+	Next(ctx context.Context, in *NextRequest, opts ...grpc.CallOption) (*Value, error)
+	// NextN reserves the count of values and returns the last one of them.
+	NextN(ctx context.Context, in *NextNRequest, opts ...grpc.CallOption) (*Value, error)
+	// Last returns the last value of the sequence.
+	Last(ctx context.Context, in *LastRequest, opts ...grpc.CallOption) (*Value, error)
+	// Forward forwards the sequence to the target value.
+	Forward(ctx context.Context, in *ForwardRequest, opts ...grpc.CallOption) (*Value, error)
+	// NextMany returns the next values of the several sequences.
+	NextMany(ctx context.Context, in *NextManyRequest, opts ...grpc.CallOption) (*NextManyResponse, error)
+	// Reserve streams the count of blocks of the size values each.
+	Reserve(ctx context.Context, in *ReserveRequest, opts ...grpc.CallOption) (SerialKey_ReserveClient, error)
+}
+
+type serialKeyClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSerialKeyClient(cc grpc.ClientConnInterface) SerialKeyClient {
+	return &serialKeyClient{cc}
+}
+
+func (c *serialKeyClient) Next(ctx context.Context, in *NextRequest, opts ...grpc.CallOption) (*Value, error) {
+	out := new(Value)
+	err := c.cc.Invoke(ctx, SerialKey_Next_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *serialKeyClient) NextN(ctx context.Context, in *NextNRequest, opts ...grpc.CallOption) (*Value, error) {
+	out := new(Value)
+	err := c.cc.Invoke(ctx, SerialKey_NextN_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *serialKeyClient) Last(ctx context.Context, in *LastRequest, opts ...grpc.CallOption) (*Value, error) {
+	out := new(Value)
+	err := c.cc.Invoke(ctx, SerialKey_Last_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *serialKeyClient) Forward(ctx context.Context, in *ForwardRequest, opts ...grpc.CallOption) (*Value, error) {
+	out := new(Value)
+	err := c.cc.Invoke(ctx, SerialKey_Forward_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *serialKeyClient) NextMany(ctx context.Context, in *NextManyRequest, opts ...grpc.CallOption) (*NextManyResponse, error) {
+	out := new(NextManyResponse)
+	err := c.cc.Invoke(ctx, SerialKey_NextMany_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *serialKeyClient) Reserve(ctx context.Context, in *ReserveRequest, opts ...grpc.CallOption) (SerialKey_ReserveClient, error) {
+	stream, err := c.cc.NewStream(ctx, &SerialKey_ServiceDesc.Streams[0], SerialKey_Reserve_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &serialKeyReserveClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type SerialKey_ReserveClient interface {
+	Recv() (*Block, error)
+	grpc.ClientStream
+}
+
+type serialKeyReserveClient struct {
+	grpc.ClientStream
+}
+
+func (x *serialKeyReserveClient) Recv() (*Block, error) {
+	m := new(Block)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// SerialKeyServer is the server API for SerialKey service.
+// All implementations must embed UnimplementedSerialKeyServer
+// for forward compatibility
+type SerialKeyServer interface {
+	// Next returns the next value of the sequence.
+	Next(context.Context, *NextRequest) (*Value, error)
+	// NextN reserves the count of values and returns the last one of them.
+	NextN(context.Context, *NextNRequest) (*Value, error)
+	// Last returns the last value of the sequence.
+	Last(context.Context, *LastRequest) (*Value, error)
+	// Forward forwards the sequence to the target value.
+	Forward(context.Context, *ForwardRequest) (*Value, error)
+	// NextMany returns the next values of the several sequences.
+	NextMany(context.Context, *NextManyRequest) (*NextManyResponse, error)
+	// Reserve streams the count of blocks of the size values each.
+	Reserve(*ReserveRequest, SerialKey_ReserveServer) error
+	mustEmbedUnimplementedSerialKeyServer()
+}
+
+// UnimplementedSerialKeyServer must be embedded to have forward compatible implementations.
+type UnimplementedSerialKeyServer struct {
+}
+
+func (UnimplementedSerialKeyServer) Next(context.Context, *NextRequest) (*Value, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Next not implemented")
+}
+func (UnimplementedSerialKeyServer) NextN(context.Context, *NextNRequest) (*Value, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method NextN not implemented")
+}
+func (UnimplementedSerialKeyServer) Last(context.Context, *LastRequest) (*Value, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Last not implemented")
+}
+func (UnimplementedSerialKeyServer) Forward(context.Context, *ForwardRequest) (*Value, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Forward not implemented")
+}
+func (UnimplementedSerialKeyServer) NextMany(context.Context, *NextManyRequest) (*NextManyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method NextMany not implemented")
+}
+func (UnimplementedSerialKeyServer) Reserve(*ReserveRequest, SerialKey_ReserveServer) error {
+	return status.Errorf(codes.Unimplemented, "method Reserve not implemented")
+}
+func (UnimplementedSerialKeyServer) mustEmbedUnimplementedSerialKeyServer() {}
+
+// UnsafeSerialKeyServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SerialKeyServer will
+// result in compilation errors.
+type UnsafeSerialKeyServer interface {
+	mustEmbedUnimplementedSerialKeyServer()
+}
+
+func RegisterSerialKeyServer(s grpc.ServiceRegistrar, srv SerialKeyServer) {
+	s.RegisterService(&SerialKey_ServiceDesc, srv)
+}
+
+func _SerialKey_Next_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NextRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SerialKeyServer).Next(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SerialKey_Next_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SerialKeyServer).Next(ctx, req.(*NextRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SerialKey_NextN_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NextNRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SerialKeyServer).NextN(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SerialKey_NextN_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SerialKeyServer).NextN(ctx, req.(*NextNRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SerialKey_Last_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LastRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SerialKeyServer).Last(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SerialKey_Last_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SerialKeyServer).Last(ctx, req.(*LastRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SerialKey_Forward_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ForwardRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SerialKeyServer).Forward(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SerialKey_Forward_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SerialKeyServer).Forward(ctx, req.(*ForwardRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SerialKey_NextMany_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NextManyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SerialKeyServer).NextMany(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SerialKey_NextMany_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SerialKeyServer).NextMany(ctx, req.(*NextManyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SerialKey_Reserve_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ReserveRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SerialKeyServer).Reserve(m, &serialKeyReserveServer{stream})
+}
+
+type SerialKey_ReserveServer interface {
+	Send(*Block) error
+	grpc.ServerStream
+}
+
+type serialKeyReserveServer struct {
+	grpc.ServerStream
+}
+
+func (x *serialKeyReserveServer) Send(m *Block) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// SerialKey_ServiceDesc is the grpc.ServiceDesc for SerialKey service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var SerialKey_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "serialkey.v1.SerialKey",
+	HandlerType: (*SerialKeyServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Next",
+			Handler:    _SerialKey_Next_Handler,
+		},
+		{
+			MethodName: "NextN",
+			Handler:    _SerialKey_NextN_Handler,
+		},
+		{
+			MethodName: "Last",
+			Handler:    _SerialKey_Last_Handler,
+		},
+		{
+			MethodName: "Forward",
+			Handler:    _SerialKey_Forward_Handler,
+		},
+		{
+			MethodName: "NextMany",
+			Handler:    _SerialKey_NextMany_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Reserve",
+			Handler:       _SerialKey_Reserve_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "serialkey.proto",
+}
@@ -0,0 +1,164 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package serialkeygrpc_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pfmt/serialkey"
+	"github.com/pfmt/serialkey/serialkeygrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const timeout = 3 * time.Second
+
+func newClient(t *testing.T, chain serialkey.Chain) *serialkeygrpc.Client {
+	ln := bufconn.Listen(1 << 20)
+
+	srv := grpc.NewServer()
+	serialkeygrpc.RegisterSerialKeyServer(srv, serialkeygrpc.NewServer(chain))
+
+	go func() { _ = srv.Serve(ln) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.Dial(
+		"bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return ln.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("dial: %s", err)
+	}
+
+	client := serialkeygrpc.NewClient(conn)
+	t.Cleanup(func() { _ = client.Close() })
+
+	return client
+}
+
+func TestClient(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var chain serialkey.Chain = newClient(t, serialkey.NewLocal(serialkey.LocalWithStart(1)))
+
+	for i := int64(1); i <= 42; i++ {
+		value, err := chain.Next(ctx, "foo")
+		if err != nil {
+			t.Fatalf("next: %s", err)
+		}
+		if value != i {
+			t.Errorf("want next value: %d, got: %d", i, value)
+		}
+	}
+
+	value, err := chain.Last(ctx, "foo")
+	if err != nil {
+		t.Fatalf("last: %s", err)
+	}
+	if value != 42 {
+		t.Errorf("want last value: 42, got: %d", value)
+	}
+
+	value, err = chain.Forward(ctx, "foo", 1000)
+	if err != nil {
+		t.Fatalf("forward: %s", err)
+	}
+	if value != 1000 {
+		t.Errorf("want forwarded value: 1000, got: %d", value)
+	}
+
+	value, err = chain.(serialkey.NextNer).NextN(ctx, "foo", 10)
+	if err != nil {
+		t.Fatalf("next n: %s", err)
+	}
+	if value != 1010 {
+		t.Errorf("want next n value: 1010, got: %d", value)
+	}
+}
+
+func TestClientNextMany(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	client := newClient(t, serialkey.NewLocal(serialkey.LocalWithStart(1)))
+
+	values, err := client.NextMany(ctx, "foo", "bar", "foo")
+	if err != nil {
+		t.Fatalf("next many: %s", err)
+	}
+
+	want := []int64{1, 1, 2}
+	for i := range want {
+		if values[i] != want[i] {
+			t.Errorf("want next many values: %v, got: %v", want, values)
+			break
+		}
+	}
+}
+
+func TestClientReserve(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	client := newClient(t, serialkey.NewLocal(serialkey.LocalWithStart(1)))
+
+	var blocks [][2]int64
+
+	err := client.Reserve(ctx, "foo", 100, 3, func(first, last int64) error {
+		blocks = append(blocks, [2]int64{first, last})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("reserve: %s", err)
+	}
+
+	want := [][2]int64{{1, 100}, {101, 200}, {201, 300}}
+	if len(blocks) != len(want) {
+		t.Fatalf("want blocks: %v, got: %v", want, blocks)
+	}
+	for i := range want {
+		if blocks[i] != want[i] {
+			t.Errorf("want blocks: %v, got: %v", want, blocks)
+			break
+		}
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	snowflake, err := serialkey.NewSnowflake()
+	if err != nil {
+		t.Fatalf("new snowflake: %s", err)
+	}
+
+	client := newClient(t, snowflake)
+
+	_, err = client.Next(ctx, "")
+	if !errors.Is(err, serialkey.ErrInvalidRequest) {
+		t.Errorf("want invalid request error, got: %v", err)
+	}
+
+	_, err = client.NextN(ctx, "foo", 10)
+	if !errors.Is(err, serialkey.ErrNotImplemented) {
+		t.Errorf("want not implemented error, got: %v", err)
+	}
+}
@@ -0,0 +1,225 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package serialkeygrpc exposes the serialkey chain over gRPC.
+package serialkeygrpc
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative serialkey.proto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/pfmt/serialkey"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// MaxKeyLength is the maximum length of the key name.
+	MaxKeyLength = 1024
+	// MaxKeys is the maximum number of the key names of the next many request.
+	MaxKeys = 1000
+	// MaxBlocks is the maximum number of the blocks of the reserve request.
+	MaxBlocks = 1000
+)
+
+// NewServer returns the gRPC server of the chain.
+func NewServer(chain serialkey.Chain) *Server {
+	return &Server{chain: chain}
+}
+
+// Server is the gRPC server exposing the chain.
+type Server struct {
+	UnimplementedSerialKeyServer
+	chain serialkey.Chain
+}
+
+// Next returns the next value of the sequence.
+func (srv *Server) Next(ctx context.Context, req *NextRequest) (*Value, error) {
+	if err := validKey(req.GetKey()); err != nil {
+		return nil, err
+	}
+
+	value, err := srv.chain.Next(ctx, req.GetKey())
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &Value{Key: req.GetKey(), Value: value}, nil
+}
+
+// NextN reserves the count of values and returns the last one of them.
+func (srv *Server) NextN(ctx context.Context, req *NextNRequest) (*Value, error) {
+	if err := validKey(req.GetKey()); err != nil {
+		return nil, err
+	}
+
+	value, err := srv.nextN(ctx, req.GetKey(), req.GetCount())
+	if err != nil {
+		return nil, err
+	}
+
+	return &Value{Key: req.GetKey(), Value: value}, nil
+}
+
+// Last returns the last value of the sequence.
+func (srv *Server) Last(ctx context.Context, req *LastRequest) (*Value, error) {
+	if err := validKey(req.GetKey()); err != nil {
+		return nil, err
+	}
+
+	value, err := srv.chain.Last(ctx, req.GetKey())
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &Value{Key: req.GetKey(), Value: value}, nil
+}
+
+// Forward forwards the sequence to the target value.
+func (srv *Server) Forward(ctx context.Context, req *ForwardRequest) (*Value, error) {
+	if err := validKey(req.GetKey()); err != nil {
+		return nil, err
+	}
+
+	value, err := srv.chain.Forward(ctx, req.GetKey(), req.GetTarget())
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &Value{Key: req.GetKey(), Value: value}, nil
+}
+
+// NextMany returns the next values of the several sequences
+// in the order of the requested key names.
+func (srv *Server) NextMany(ctx context.Context, req *NextManyRequest) (*NextManyResponse, error) {
+	keys := req.GetKeys()
+
+	if len(keys) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "missing keys")
+	}
+
+	if len(keys) > MaxKeys {
+		return nil, status.Errorf(codes.InvalidArgument, "more than %d keys", MaxKeys)
+	}
+
+	for _, key := range keys {
+		if err := validKey(key); err != nil {
+			return nil, err
+		}
+	}
+
+	res := &NextManyResponse{Values: make([]*Value, 0, len(keys))}
+
+	for _, key := range keys {
+		value, err := srv.chain.Next(ctx, key)
+		if err != nil {
+			return nil, statusError(err)
+		}
+
+		res.Values = append(res.Values, &Value{Key: key, Value: value})
+	}
+
+	return res, nil
+}
+
+// Reserve streams the count of blocks of the size values each.
+func (srv *Server) Reserve(req *ReserveRequest, stream SerialKey_ReserveServer) error {
+	if err := validKey(req.GetKey()); err != nil {
+		return err
+	}
+
+	if req.GetCount() <= 0 || req.GetCount() > MaxBlocks {
+		return status.Errorf(codes.InvalidArgument, "count must be in range [1,%d]: %d", MaxBlocks, req.GetCount())
+	}
+
+	ctx := stream.Context()
+
+	for i := int64(0); i < req.GetCount(); i++ {
+		last, err := srv.nextN(ctx, req.GetKey(), req.GetSize())
+		if err != nil {
+			return err
+		}
+
+		err = stream.Send(&Block{Key: req.GetKey(), First: last - req.GetSize() + 1, Last: last})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (srv *Server) nextN(ctx context.Context, key string, count int64) (int64, error) {
+	chain, ok := srv.chain.(serialkey.NextNer)
+	if !ok {
+		return 0, status.Error(codes.Unimplemented, "next N method is not implemented by the chain")
+	}
+
+	if count <= 0 {
+		return 0, status.Errorf(codes.InvalidArgument, "count must be positive: %d", count)
+	}
+
+	value, err := chain.NextN(ctx, key, count)
+	if err != nil {
+		return 0, statusError(err)
+	}
+
+	return value, nil
+}
+
+func validKey(key string) error {
+	if key == "" {
+		return status.Error(codes.InvalidArgument, "missing key")
+	}
+
+	if len(key) > MaxKeyLength {
+		return status.Errorf(codes.InvalidArgument, "key is longer than %d bytes", MaxKeyLength)
+	}
+
+	return nil
+}
+
+// statusError returns the gRPC status error of the chain error.
+func statusError(err error) error {
+	switch {
+	case errors.Is(err, serialkey.ErrInvalidRequest):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, serialkey.ErrNotImplemented):
+		return status.Error(codes.Unimplemented, err.Error())
+	case errors.Is(err, serialkey.ErrUnavailable):
+		return status.Error(codes.Unavailable, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+// chainError returns the chain error of the gRPC status error.
+func chainError(method string, err error) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+
+	switch st.Code() {
+	case codes.InvalidArgument:
+		return fmt.Errorf("%s: %w: %s", method, serialkey.ErrInvalidRequest, st.Message())
+	case codes.Unimplemented:
+		return fmt.Errorf("%s: %w: %s", method, serialkey.ErrNotImplemented, st.Message())
+	case codes.Unavailable:
+		return fmt.Errorf("%s: %w: %s", method, serialkey.ErrUnavailable, st.Message())
+	case codes.DeadlineExceeded:
+		return fmt.Errorf("%s: %w: %s", method, context.DeadlineExceeded, st.Message())
+	case codes.Canceled:
+		return fmt.Errorf("%s: %w: %s", method, context.Canceled, st.Message())
+	default:
+		return fmt.Errorf("%s: %w", method, err)
+	}
+}
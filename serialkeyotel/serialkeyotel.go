@@ -0,0 +1,175 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package serialkeyotel traces the calls of the serialkey chains
+// by the OpenTelemetry spans.
+package serialkeyotel
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pfmt/serialkey"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// New returns the chain starting the span per call of the chain.
+func New(chain serialkey.Chain, opts ...Option) *Chain {
+	cfg := Configuration{tracerProvider: otel.GetTracerProvider()}
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	var attrs []attribute.KeyValue
+
+	if c, ok := chain.(interface{ Table() string }); ok {
+		attrs = append(attrs, attribute.String("serialkey.table", c.Table()))
+	}
+
+	return &Chain{
+		chain:  chain,
+		tracer: cfg.tracerProvider.Tracer(serialkey.TracerName),
+		attrs:  attrs,
+	}
+}
+
+// Chain is the serialkeys keychain starting the span
+// per call of the underlying chain.
+type Chain struct {
+	chain  serialkey.Chain
+	tracer trace.Tracer
+	attrs  []attribute.KeyValue
+}
+
+// Next for the passed key name returns an value guaranteed to be greater
+// than the value returned for the same key name passed at the time
+// of previous call of the next method or the forward method.
+// The next method is thread safe.
+func (chain *Chain) Next(ctx context.Context, key string) (int64, error) {
+	ctx, span := chain.start(ctx, "serialkey.Next", key)
+	defer span.End()
+
+	value, err := chain.chain.Next(ctx, key)
+	return value, end(span, value, err)
+}
+
+// NextN for the passed key name reserves the count of values
+// and returns the last one of them.
+// The next N method is thread safe.
+func (chain *Chain) NextN(ctx context.Context, key string, count int64) (int64, error) {
+	ctx, span := chain.start(ctx, "serialkey.NextN", key, attribute.Int64("serialkey.count", count))
+	defer span.End()
+
+	c, ok := chain.chain.(serialkey.NextNer)
+	if !ok {
+		return 0, fail(span, fmt.Errorf("next n: %w", serialkey.ErrNotImplemented))
+	}
+
+	value, err := c.NextN(ctx, key, count)
+	return value, end(span, value, err)
+}
+
+// Last for the passed key name returns the value returned for
+// the same key name passed at the time of previous call
+// of the next method or the forward method.
+// The last method is thread safe.
+func (chain *Chain) Last(ctx context.Context, key string) (int64, error) {
+	ctx, span := chain.start(ctx, "serialkey.Last", key)
+	defer span.End()
+
+	value, err := chain.chain.Last(ctx, key)
+	return value, end(span, value, err)
+}
+
+// Forward for the passed key name returns an value guaranteed
+// to be greater or equal to the target value and guaranteed to be greater
+// than the value returned for the same key name passed at the time
+// of previous call of the forward method or the next method.
+// The forward method is thread safe.
+func (chain *Chain) Forward(ctx context.Context, key string, target int64) (int64, error) {
+	ctx, span := chain.start(ctx, "serialkey.Forward", key, attribute.Int64("serialkey.target", target))
+	defer span.End()
+
+	value, err := chain.chain.Forward(ctx, key, target)
+	return value, end(span, value, err)
+}
+
+// CreateTable creates the table of the underlying chain
+// if the chain supports the table creation.
+// The create table method is thread safe.
+func (chain *Chain) CreateTable(ctx context.Context) error {
+	ctx, span := chain.tracer.Start(ctx, "serialkey.CreateTable", trace.WithAttributes(chain.attrs...))
+	defer span.End()
+
+	c, ok := chain.chain.(interface{ CreateTable(context.Context) error })
+	if !ok {
+		return fail(span, fmt.Errorf("create table: %w", serialkey.ErrNotImplemented))
+	}
+
+	return fail(span, c.CreateTable(ctx))
+}
+
+// Health checks the health of the underlying chain within the span.
+// The health method is thread safe.
+func (chain *Chain) Health(ctx context.Context) error {
+	ctx, span := chain.tracer.Start(ctx, "serialkey.Health", trace.WithAttributes(chain.attrs...))
+	defer span.End()
+
+	return fail(span, serialkey.Health(ctx, chain.chain))
+}
+
+// Durable reports whether the underlying chain is durable.
+// The durable method is thread safe.
+func (chain *Chain) Durable() bool {
+	return serialkey.Durable(chain.chain)
+}
+
+// Close closes the underlying chain.
+// The close method is thread safe.
+func (chain *Chain) Close() error {
+	return chain.chain.Close()
+}
+
+func (chain *Chain) start(ctx context.Context, name, key string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs, attribute.String("serialkey.key", key))
+	attrs = append(attrs, chain.attrs...)
+	return chain.tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// end records the value or the error of the call to the span.
+func end(span trace.Span, value int64, err error) error {
+	if err != nil {
+		return fail(span, err)
+	}
+
+	span.SetAttributes(attribute.Int64("serialkey.value", value))
+
+	return nil
+}
+
+// fail records the error of the call to the span.
+func fail(span trace.Span, err error) error {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+// Option changes configuration.
+type Option func(*Configuration)
+
+// Configuration holds values changeable by options.
+type Configuration struct {
+	tracerProvider trace.TracerProvider
+}
+
+// WithTracerProvider sets the OpenTelemetry tracer provider.
+func WithTracerProvider(tracerProvider trace.TracerProvider) Option {
+	return func(cfg *Configuration) { cfg.tracerProvider = tracerProvider }
+}
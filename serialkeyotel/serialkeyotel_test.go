@@ -0,0 +1,161 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package serialkeyotel_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pfmt/serialkey"
+	"github.com/pfmt/serialkey/serialkeyotel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+const timeout = 3 * time.Second
+
+func newChain(t *testing.T, chain serialkey.Chain) (*serialkeyotel.Chain, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+
+	c := serialkeyotel.New(chain, serialkeyotel.WithTracerProvider(provider))
+	t.Cleanup(func() { _ = c.Close() })
+
+	return c, exporter
+}
+
+func TestChain(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	chain, exporter := newChain(t, serialkey.NewLocal(serialkey.LocalWithStart(1)))
+
+	_, err := chain.Next(ctx, "foo")
+	if err != nil {
+		t.Fatalf("next: %s", err)
+	}
+
+	_, err = chain.NextN(ctx, "foo", 10)
+	if err != nil {
+		t.Fatalf("next n: %s", err)
+	}
+
+	_, err = chain.Forward(ctx, "foo", 42)
+	if err != nil {
+		t.Fatalf("forward: %s", err)
+	}
+
+	_, err = chain.Last(ctx, "foo")
+	if err != nil {
+		t.Fatalf("last: %s", err)
+	}
+
+	want := []struct {
+		name  string
+		attrs []attribute.KeyValue
+	}{
+		{
+			name: "serialkey.Next",
+			attrs: []attribute.KeyValue{
+				attribute.String("serialkey.key", "foo"),
+				attribute.Int64("serialkey.value", 1),
+			},
+		},
+		{
+			name: "serialkey.NextN",
+			attrs: []attribute.KeyValue{
+				attribute.Int64("serialkey.count", 10),
+				attribute.String("serialkey.key", "foo"),
+				attribute.Int64("serialkey.value", 11),
+			},
+		},
+		{
+			name: "serialkey.Forward",
+			attrs: []attribute.KeyValue{
+				attribute.Int64("serialkey.target", 42),
+				attribute.String("serialkey.key", "foo"),
+				attribute.Int64("serialkey.value", 42),
+			},
+		},
+		{
+			name: "serialkey.Last",
+			attrs: []attribute.KeyValue{
+				attribute.String("serialkey.key", "foo"),
+				attribute.Int64("serialkey.value", 42),
+			},
+		},
+	}
+
+	spans := exporter.GetSpans()
+
+	if len(spans) != len(want) {
+		t.Fatalf("want spans: %d, got: %d", len(want), len(spans))
+	}
+
+	for i, w := range want {
+		if spans[i].Name != w.name {
+			t.Errorf("want span name: %s, got: %s", w.name, spans[i].Name)
+		}
+		if spans[i].Status.Code != codes.Unset {
+			t.Errorf("want span %s status: unset, got: %s", w.name, spans[i].Status.Code)
+		}
+		if len(spans[i].Attributes) != len(w.attrs) {
+			t.Errorf("want span %s attributes: %v, got: %v", w.name, w.attrs, spans[i].Attributes)
+			continue
+		}
+		for j := range w.attrs {
+			if spans[i].Attributes[j] != w.attrs[j] {
+				t.Errorf("want span %s attributes: %v, got: %v", w.name, w.attrs, spans[i].Attributes)
+				break
+			}
+		}
+	}
+}
+
+func TestChainErrors(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	snowflake, err := serialkey.NewSnowflake()
+	if err != nil {
+		t.Fatalf("new snowflake: %s", err)
+	}
+
+	chain, exporter := newChain(t, snowflake)
+
+	_, err = chain.NextN(ctx, "foo", 10)
+	if !errors.Is(err, serialkey.ErrNotImplemented) {
+		t.Errorf("want not implemented error, got: %v", err)
+	}
+
+	err = chain.CreateTable(ctx)
+	if !errors.Is(err, serialkey.ErrNotImplemented) {
+		t.Errorf("want not implemented error, got: %v", err)
+	}
+
+	spans := exporter.GetSpans()
+
+	if len(spans) != 2 {
+		t.Fatalf("want spans: 2, got: %d", len(spans))
+	}
+
+	for _, span := range spans {
+		if span.Status.Code != codes.Error {
+			t.Errorf("want span %s status: error, got: %s", span.Name, span.Status.Code)
+		}
+		if len(span.Events) != 1 || span.Events[0].Name != "exception" {
+			t.Errorf("want span %s exception event, got: %v", span.Name, span.Events)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package serialkeyprom
+
+import (
+	"github.com/pfmt/serialkey"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// NewPgxPoolCollector returns the Prometheus collector
+// of the statistics of the pgx pool underlying the chain.
+func NewPgxPoolCollector(chain *serialkey.PgxPool, opts ...Option) *PgxPoolCollector {
+	cfg := Configuration{namespace: Namespace}
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(cfg.namespace, "pgxpool", name), help, nil, nil)
+	}
+
+	return &PgxPoolCollector{
+		chain:                chain,
+		acquireCount:         desc("acquire_count_total", "Number of the successful connection acquires from the pool."),
+		acquireDuration:      desc("acquire_duration_seconds_total", "Total duration of the successful connection acquires from the pool."),
+		acquiredConns:        desc("acquired_conns", "Number of the currently acquired connections in the pool."),
+		canceledAcquireCount: desc("canceled_acquire_count_total", "Number of the connection acquires from the pool canceled by the context."),
+		constructingConns:    desc("constructing_conns", "Number of the connections under construction in the pool."),
+		emptyAcquireCount:    desc("empty_acquire_count_total", "Number of the connection acquires waited for the connection because the pool was empty."),
+		idleConns:            desc("idle_conns", "Number of the currently idle connections in the pool."),
+		maxConns:             desc("max_conns", "Maximum size of the pool."),
+		totalConns:           desc("total_conns", "Total number of the connections in the pool."),
+	}
+}
+
+// PgxPoolCollector is the Prometheus collector
+// of the statistics of the pgx pool underlying the chain.
+type PgxPoolCollector struct {
+	chain                *serialkey.PgxPool
+	acquireCount         *prometheus.Desc
+	acquireDuration      *prometheus.Desc
+	acquiredConns        *prometheus.Desc
+	canceledAcquireCount *prometheus.Desc
+	constructingConns    *prometheus.Desc
+	emptyAcquireCount    *prometheus.Desc
+	idleConns            *prometheus.Desc
+	maxConns             *prometheus.Desc
+	totalConns           *prometheus.Desc
+}
+
+// Describe implements the Prometheus collector.
+func (c *PgxPoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.acquireCount
+	ch <- c.acquireDuration
+	ch <- c.acquiredConns
+	ch <- c.canceledAcquireCount
+	ch <- c.constructingConns
+	ch <- c.emptyAcquireCount
+	ch <- c.idleConns
+	ch <- c.maxConns
+	ch <- c.totalConns
+}
+
+// Collect implements the Prometheus collector.
+func (c *PgxPoolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.chain.Stat()
+
+	ch <- prometheus.MustNewConstMetric(c.acquireCount, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireDuration, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(c.acquiredConns, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.canceledAcquireCount, prometheus.CounterValue, float64(stat.CanceledAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.constructingConns, prometheus.GaugeValue, float64(stat.ConstructingConns()))
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquireCount, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.maxConns, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(stat.TotalConns()))
+}
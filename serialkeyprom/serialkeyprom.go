@@ -0,0 +1,303 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package serialkeyprom instruments the serialkey chains
+// by the Prometheus metrics.
+package serialkeyprom
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pfmt/serialkey"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// Namespace is the default namespace of the metrics.
+	Namespace = "serialkey"
+	// MaxKeys is the default maximum number of the distinct key labels.
+	MaxKeys = 100
+	// OtherKey is the key label of the keys exceeding the maximum number
+	// of the distinct key labels.
+	OtherKey = "other"
+)
+
+// New returns the chain recording the metrics of the calls of the chain,
+// the returned chain is the Prometheus collector
+// to be registered by the Prometheus registry.
+func New(chain serialkey.Chain, opts ...Option) *Chain {
+	cfg := Configuration{
+		namespace: Namespace,
+		keyLabel:  func(key string) string { return key },
+		maxKeys:   MaxKeys,
+		buckets:   prometheus.DefBuckets,
+	}
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return &Chain{
+		chain:    chain,
+		keyLabel: cfg.keyLabel,
+		maxKeys:  cfg.maxKeys,
+		keys:     make(map[string]struct{}),
+		calls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: cfg.namespace,
+			Name:      "calls_total",
+			Help:      "Number of the chain calls by the method.",
+		}, []string{"method"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: cfg.namespace,
+			Name:      "errors_total",
+			Help:      "Number of the failed chain calls by the method.",
+		}, []string{"method"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: cfg.namespace,
+			Name:      "call_duration_seconds",
+			Help:      "Latency of the chain calls by the method.",
+			Buckets:   cfg.buckets,
+		}, []string{"method"}),
+		values: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: cfg.namespace,
+			Name:      "values_total",
+			Help:      "Number of the values issued by the key label.",
+		}, []string{"key"}),
+		blockHits: prometheus.NewDesc(
+			prometheus.BuildFQName(cfg.namespace, "block", "hits_total"),
+			"Number of the calls served from the block cache.",
+			nil, nil,
+		),
+		blockMisses: prometheus.NewDesc(
+			prometheus.BuildFQName(cfg.namespace, "block", "misses_total"),
+			"Number of the calls reserved the new block.",
+			nil, nil,
+		),
+	}
+}
+
+// Chain is the serialkeys keychain recording the metrics of the calls
+// of the underlying chain.
+type Chain struct {
+	sync.Mutex
+	chain       serialkey.Chain
+	keyLabel    func(string) string
+	maxKeys     int
+	keys        map[string]struct{}
+	calls       *prometheus.CounterVec
+	errors      *prometheus.CounterVec
+	duration    *prometheus.HistogramVec
+	values      *prometheus.CounterVec
+	blockHits   *prometheus.Desc
+	blockMisses *prometheus.Desc
+}
+
+// Next for the passed key name returns an value guaranteed to be greater
+// than the value returned for the same key name passed at the time
+// of previous call of the next method or the forward method.
+// The next method is thread safe.
+func (chain *Chain) Next(ctx context.Context, key string) (int64, error) {
+	var value int64
+
+	err := chain.observe("next", func() (err error) {
+		value, err = chain.chain.Next(ctx, key)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	chain.values.WithLabelValues(chain.label(key)).Inc()
+
+	return value, nil
+}
+
+// NextN for the passed key name reserves the count of values
+// and returns the last one of them.
+// The next N method is thread safe.
+func (chain *Chain) NextN(ctx context.Context, key string, count int64) (int64, error) {
+	var value int64
+
+	err := chain.observe("next_n", func() (err error) {
+		c, ok := chain.chain.(serialkey.NextNer)
+		if !ok {
+			return fmt.Errorf("next n: %w", serialkey.ErrNotImplemented)
+		}
+		value, err = c.NextN(ctx, key, count)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	chain.values.WithLabelValues(chain.label(key)).Add(float64(count))
+
+	return value, nil
+}
+
+// Last for the passed key name returns the value returned for
+// the same key name passed at the time of previous call
+// of the next method or the forward method.
+// The last method is thread safe.
+func (chain *Chain) Last(ctx context.Context, key string) (int64, error) {
+	var value int64
+
+	err := chain.observe("last", func() (err error) {
+		value, err = chain.chain.Last(ctx, key)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return value, nil
+}
+
+// Forward for the passed key name returns an value guaranteed
+// to be greater or equal to the target value and guaranteed to be greater
+// than the value returned for the same key name passed at the time
+// of previous call of the forward method or the next method.
+// The forward method is thread safe.
+func (chain *Chain) Forward(ctx context.Context, key string, target int64) (int64, error) {
+	var value int64
+
+	err := chain.observe("forward", func() (err error) {
+		value, err = chain.chain.Forward(ctx, key, target)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	chain.values.WithLabelValues(chain.label(key)).Inc()
+
+	return value, nil
+}
+
+// Health checks the health of the underlying chain.
+// The health method is thread safe.
+func (chain *Chain) Health(ctx context.Context) error {
+	return serialkey.Health(ctx, chain.chain)
+}
+
+// Durable reports whether the underlying chain is durable.
+// The durable method is thread safe.
+func (chain *Chain) Durable() bool {
+	return serialkey.Durable(chain.chain)
+}
+
+// Close closes the underlying chain.
+// The close method is thread safe.
+func (chain *Chain) Close() error {
+	return chain.observe("close", chain.chain.Close)
+}
+
+// Describe implements the Prometheus collector.
+func (chain *Chain) Describe(ch chan<- *prometheus.Desc) {
+	chain.calls.Describe(ch)
+	chain.errors.Describe(ch)
+	chain.duration.Describe(ch)
+	chain.values.Describe(ch)
+
+	if _, ok := chain.chain.(serialkey.BlockStater); ok {
+		ch <- chain.blockHits
+		ch <- chain.blockMisses
+	}
+}
+
+// Collect implements the Prometheus collector.
+func (chain *Chain) Collect(ch chan<- prometheus.Metric) {
+	chain.calls.Collect(ch)
+	chain.errors.Collect(ch)
+	chain.duration.Collect(ch)
+	chain.values.Collect(ch)
+
+	if c, ok := chain.chain.(serialkey.BlockStater); ok {
+		stats := c.BlockStats()
+		ch <- prometheus.MustNewConstMetric(chain.blockHits, prometheus.CounterValue, float64(stats.Hits))
+		ch <- prometheus.MustNewConstMetric(chain.blockMisses, prometheus.CounterValue, float64(stats.Misses))
+	}
+}
+
+func (chain *Chain) observe(method string, f func() error) error {
+	start := time.Now()
+	err := f()
+	chain.duration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+
+	chain.calls.WithLabelValues(method).Inc()
+	if err != nil {
+		chain.errors.WithLabelValues(method).Inc()
+	}
+
+	return err
+}
+
+// label returns the key label of the key name mapped by the key label mapper,
+// the key labels exceeding the maximum number of the distinct key labels
+// are replaced by the other key label.
+func (chain *Chain) label(key string) string {
+	label := chain.keyLabel(key)
+
+	chain.Lock()
+	defer chain.Unlock()
+
+	if _, ok := chain.keys[label]; ok {
+		return label
+	}
+
+	if len(chain.keys) >= chain.maxKeys {
+		return OtherKey
+	}
+
+	chain.keys[label] = struct{}{}
+
+	return label
+}
+
+// KeyPrefix returns the key label mapper which maps the key name
+// to the part of the key name before the first separator,
+// so the key names like "invoice:2022-01" are grouped by "invoice".
+func KeyPrefix(sep string) func(string) string {
+	return func(key string) string {
+		if i := strings.Index(key, sep); i >= 0 {
+			return key[:i]
+		}
+		return key
+	}
+}
+
+// Option changes configuration.
+type Option func(*Configuration)
+
+// Configuration holds values changeable by options.
+type Configuration struct {
+	namespace string
+	keyLabel  func(string) string
+	maxKeys   int
+	buckets   []float64
+}
+
+// WithNamespace sets the namespace of the metrics.
+func WithNamespace(namespace string) Option {
+	return func(cfg *Configuration) { cfg.namespace = namespace }
+}
+
+// WithKeyLabel sets the mapper of the key names to the key labels.
+func WithKeyLabel(keyLabel func(string) string) Option {
+	return func(cfg *Configuration) { cfg.keyLabel = keyLabel }
+}
+
+// WithMaxKeys sets the maximum number of the distinct key labels.
+func WithMaxKeys(maxKeys int) Option {
+	return func(cfg *Configuration) { cfg.maxKeys = maxKeys }
+}
+
+// WithBuckets sets the buckets of the latency histograms.
+func WithBuckets(buckets []float64) Option {
+	return func(cfg *Configuration) { cfg.buckets = buckets }
+}
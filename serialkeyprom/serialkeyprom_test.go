@@ -0,0 +1,212 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package serialkeyprom_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pfmt/serialkey"
+	"github.com/pfmt/serialkey/serialkeybolt"
+	"github.com/pfmt/serialkey/serialkeyprom"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	bolt "go.etcd.io/bbolt"
+)
+
+const timeout = 3 * time.Second
+
+func TestChain(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	chain := serialkeyprom.New(
+		serialkey.NewLocal(serialkey.LocalWithStart(1)),
+		serialkeyprom.WithKeyLabel(serialkeyprom.KeyPrefix(":")),
+		serialkeyprom.WithMaxKeys(2),
+	)
+	defer chain.Close()
+
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(chain)
+
+	for _, key := range []string{"foo:1", "foo:2", "bar:1", "xyz:1"} {
+		_, err := chain.Next(ctx, key)
+		if err != nil {
+			t.Fatalf("next: %s", err)
+		}
+	}
+
+	_, err := chain.NextN(ctx, "foo:1", 10)
+	if err != nil {
+		t.Fatalf("next n: %s", err)
+	}
+
+	_, err = chain.Forward(ctx, "bar:1", 42)
+	if err != nil {
+		t.Fatalf("forward: %s", err)
+	}
+
+	_, err = chain.Last(ctx, "foo:1")
+	if err != nil {
+		t.Fatalf("last: %s", err)
+	}
+
+	want := `
+# HELP serialkey_calls_total Number of the chain calls by the method.
+# TYPE serialkey_calls_total counter
+serialkey_calls_total{method="forward"} 1
+serialkey_calls_total{method="last"} 1
+serialkey_calls_total{method="next"} 4
+serialkey_calls_total{method="next_n"} 1
+# HELP serialkey_values_total Number of the values issued by the key label.
+# TYPE serialkey_values_total counter
+serialkey_values_total{key="bar"} 2
+serialkey_values_total{key="foo"} 12
+serialkey_values_total{key="other"} 1
+`
+
+	err = testutil.GatherAndCompare(reg, strings.NewReader(want), "serialkey_calls_total", "serialkey_values_total")
+	if err != nil {
+		t.Error(err)
+	}
+
+	n, err := testutil.GatherAndCount(reg, "serialkey_call_duration_seconds")
+	if err != nil {
+		t.Fatalf("gather: %s", err)
+	}
+	if n != 4 {
+		t.Errorf("want latency histograms: 4, got: %d", n)
+	}
+
+	n, err = testutil.GatherAndCount(reg, "serialkey_block_hits_total")
+	if err != nil {
+		t.Fatalf("gather: %s", err)
+	}
+	if n != 0 {
+		t.Errorf("want no block cache metrics of the local chain, got: %d", n)
+	}
+}
+
+func TestChainErrors(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	snowflake, err := serialkey.NewSnowflake()
+	if err != nil {
+		t.Fatalf("new snowflake: %s", err)
+	}
+
+	chain := serialkeyprom.New(snowflake, serialkeyprom.WithNamespace("test"))
+	defer chain.Close()
+
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(chain)
+
+	_, err = chain.NextN(ctx, "foo", 10)
+	if !errors.Is(err, serialkey.ErrNotImplemented) {
+		t.Errorf("want not implemented error, got: %v", err)
+	}
+
+	want := `
+# HELP test_errors_total Number of the failed chain calls by the method.
+# TYPE test_errors_total counter
+test_errors_total{method="next_n"} 1
+`
+
+	err = testutil.GatherAndCompare(reg, strings.NewReader(want), "test_errors_total")
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestChainBlock(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "serialkeys.db"), 0o600, &bolt.Options{NoSync: true})
+	if err != nil {
+		t.Fatalf("open bolt: %s", err)
+	}
+	db.MaxBatchDelay = time.Millisecond
+
+	chain := serialkeyprom.New(serialkeybolt.New(db, serialkeybolt.WithStart(1), serialkeybolt.WithBlock(10)))
+	defer chain.Close()
+
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(chain)
+
+	for i := 0; i < 25; i++ {
+		_, err := chain.Next(ctx, "foo")
+		if err != nil {
+			t.Fatalf("next: %s", err)
+		}
+	}
+
+	want := `
+# HELP serialkey_block_hits_total Number of the calls served from the block cache.
+# TYPE serialkey_block_hits_total counter
+serialkey_block_hits_total 22
+# HELP serialkey_block_misses_total Number of the calls reserved the new block.
+# TYPE serialkey_block_misses_total counter
+serialkey_block_misses_total 3
+`
+
+	err = testutil.GatherAndCompare(reg, strings.NewReader(want), "serialkey_block_hits_total", "serialkey_block_misses_total")
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPgxPoolCollector(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	pool, err := pgxpool.New(ctx, "postgres://postgres@127.0.0.1:1/postgres?pool_max_conns=3")
+	if err != nil {
+		t.Fatalf("new pgx pool: %s", err)
+	}
+
+	chain := serialkey.NewPgxPool(pool)
+	defer chain.Close()
+
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(serialkeyprom.NewPgxPoolCollector(chain))
+
+	want := `
+# HELP serialkey_pgxpool_max_conns Maximum size of the pool.
+# TYPE serialkey_pgxpool_max_conns gauge
+serialkey_pgxpool_max_conns 3
+# HELP serialkey_pgxpool_total_conns Total number of the connections in the pool.
+# TYPE serialkey_pgxpool_total_conns gauge
+serialkey_pgxpool_total_conns 0
+`
+
+	err = testutil.GatherAndCompare(reg, strings.NewReader(want), "serialkey_pgxpool_max_conns", "serialkey_pgxpool_total_conns")
+	if err != nil {
+		t.Error(err)
+	}
+
+	n, err := testutil.GatherAndCount(reg)
+	if err != nil {
+		t.Fatalf("gather: %s", err)
+	}
+	if n != 9 {
+		t.Errorf("want pgx pool metrics: 9, got: %d", n)
+	}
+}
@@ -0,0 +1,21 @@
+local function less(a, b)
+   local negative = string.sub(a, 1, 1) == '-'
+   if negative ~= (string.sub(b, 1, 1) == '-') then
+      return negative
+   end
+   if negative then
+      a, b = string.sub(b, 2), string.sub(a, 2)
+   end
+   if #a ~= #b then
+      return #a < #b
+   end
+   return a < b
+end
+
+local current = redis.call('GET', KEYS[1])
+if current and not less(current, ARGV[1]) then
+   redis.call('INCR', KEYS[1])
+   return redis.call('GET', KEYS[1])
+end
+redis.call('SET', KEYS[1], ARGV[1])
+return ARGV[1]
@@ -0,0 +1,5 @@
+if redis.call('EXISTS', KEYS[1]) == 0 then
+   redis.call('SET', KEYS[1], ARGV[1])
+end
+redis.call('INCRBY', KEYS[1], ARGV[2])
+return redis.call('GET', KEYS[1])
@@ -0,0 +1,176 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package serialkeyredis provides the serialkey chain
+// based on the Redis scripts.
+package serialkeyredis
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+//go:embed redis_next.lua
+var redisNext string
+
+//go:embed redis_forward.lua
+var redisForward string
+
+var (
+	redisNextScript    = redis.NewScript(redisNext)
+	redisForwardScript = redis.NewScript(redisForward)
+)
+
+// New returns the serialkeys keychain based on the Redis client.
+func New(client redis.UniversalClient, opts ...Option) *Chain {
+	var cfg Configuration
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return &Chain{
+		start:  cfg.start,
+		prefix: cfg.prefix,
+		client: client,
+	}
+}
+
+// Chain is the serialkeys keychain based on the Redis client.
+type Chain struct {
+	sync.RWMutex
+	start  int64
+	prefix string
+	client redis.UniversalClient
+	closed bool
+}
+
+// Next for the passed key name returns an value guaranteed to be greater
+// than the value returned for the same key name passed at the time
+// of previous call of the next method or the forward method.
+// The next method is thread safe.
+func (chain *Chain) Next(ctx context.Context, key string) (int64, error) {
+	value, err := chain.nextN(ctx, key, 1)
+	if err != nil {
+		return 0, fmt.Errorf("fetch next value %s: %w", key, err)
+	}
+	return value, nil
+}
+
+// NextN for the passed key name reserves the count of values
+// and returns the last one of them.
+// The next N method is thread safe.
+func (chain *Chain) NextN(ctx context.Context, key string, count int64) (int64, error) {
+	value, err := chain.nextN(ctx, key, count)
+	if err != nil {
+		return 0, fmt.Errorf("fetch next values %s: %w", key, err)
+	}
+	return value, nil
+}
+
+func (chain *Chain) nextN(ctx context.Context, key string, count int64) (int64, error) {
+	keys := []string{chain.prefix + key}
+	args := []any{strconv.FormatInt(chain.start-1, 10), strconv.FormatInt(count, 10)}
+
+	s, err := redisNextScript.Run(ctx, chain.client, keys, args...).Text()
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseInt(s, 10, 64)
+}
+
+// Last for the passed key name returns the value returned for
+// the same key name passed at the time of previous call
+// of the next method or the forward method.
+// The last method is thread safe.
+func (chain *Chain) Last(ctx context.Context, key string) (int64, error) {
+	value, err := chain.client.Get(ctx, chain.prefix+key).Int64()
+	if errors.Is(err, redis.Nil) {
+		return chain.start - 1, nil
+
+	} else if err != nil {
+		return 0, fmt.Errorf("fetch last value %s: %w", key, err)
+	}
+
+	return value, nil
+}
+
+// Forward for the passed key name returns an value guaranteed
+// to be greater or equal to the target value and guaranteed to be greater
+// than the value returned for the same key name passed at the time
+// of previous call of the forward method or the next method.
+// The forward method is thread safe.
+func (chain *Chain) Forward(ctx context.Context, key string, target int64) (int64, error) {
+	keys := []string{chain.prefix + key}
+
+	s, err := redisForwardScript.Run(ctx, chain.client, keys, strconv.FormatInt(target, 10)).Text()
+	if err != nil {
+		return 0, fmt.Errorf("forward value %s to %d: %w", key, target, err)
+	}
+
+	value, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("forward value %s to %d: %w", key, target, err)
+	}
+
+	return value, nil
+}
+
+// Durable reports the chain is durable, the values are stored
+// on the Redis server, which must persist them by the append-only file.
+// The durable method is thread safe.
+func (*Chain) Durable() bool {
+	return true
+}
+
+// Health checks the connectivity by the ping.
+// The health method is thread safe.
+func (chain *Chain) Health(ctx context.Context) error {
+	err := chain.client.Ping(ctx).Err()
+	if err != nil {
+		return fmt.Errorf("check health: %w", err)
+	}
+	return nil
+}
+
+// Close closes Redis client.
+// The close method is thread safe.
+func (chain *Chain) Close() error {
+	chain.Lock()
+	defer chain.Unlock()
+
+	if chain.closed {
+		return nil
+	}
+
+	chain.closed = true
+
+	return chain.client.Close()
+}
+
+// Option changes configuration.
+type Option func(*Configuration)
+
+// Configuration holds values changeable by options.
+type Configuration struct {
+	start  int64
+	prefix string
+}
+
+// WithStart sets the start number.
+func WithStart(start int64) Option {
+	return func(cfg *Configuration) { cfg.start = start }
+}
+
+// WithPrefix sets the prefix of the Redis keys.
+func WithPrefix(prefix string) Option {
+	return func(cfg *Configuration) { cfg.prefix = prefix }
+}
@@ -0,0 +1,96 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package serialkeyredis_test
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/pfmt/serialkey/internal/serialkeytest"
+	"github.com/pfmt/serialkey/serialkeyredis"
+	"github.com/redis/go-redis/v9"
+)
+
+var redisOpt = serialkeyredis.WithStart(1)
+
+func newRedis(t testing.TB, opts ...serialkeyredis.Option) (*serialkeyredis.Chain, *miniredis.Miniredis) {
+	mr := miniredis.NewMiniRedis()
+
+	err := mr.Start()
+	if err != nil {
+		t.Fatalf("start miniredis: %s", err)
+	}
+	t.Cleanup(mr.Close)
+
+	return serialkeyredis.New(redis.NewClient(&redis.Options{Addr: mr.Addr()}), opts...), mr
+}
+
+func TestRedis(t *testing.T) {
+	chain, _ := newRedis(t, redisOpt)
+	t.Cleanup(func() { _ = chain.Close() })
+	serialkeytest.Next(t, chain)
+	serialkeytest.Forward(t, chain)
+}
+
+func BenchmarkRedisNext(b *testing.B) {
+	chain, _ := newRedis(b, redisOpt)
+	b.Cleanup(func() { _ = chain.Close() })
+	serialkeytest.BenchmarkNext(b, chain)
+}
+
+func TestRedisForward(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), serialkeytest.Timeout)
+	defer cancel()
+
+	chain, mr := newRedis(t, redisOpt, serialkeyredis.WithPrefix("serialkeys:"))
+	defer chain.Close()
+
+	value, err := chain.NextN(ctx, "foo", 42)
+	if err != nil {
+		t.Fatalf("next n: %s", err)
+	}
+	if value != 42 {
+		t.Errorf("want next n value: 42, got: %d", value)
+	}
+
+	if s, err := mr.Get("serialkeys:foo"); err != nil || s != "42" {
+		t.Errorf("want prefixed key value: 42, got: %q %v", s, err)
+	}
+
+	for _, tt := range []struct{ target, want int64 }{
+		{target: 10, want: 43},
+		{target: 100, want: 100},
+		{target: math.MaxInt64 - 1, want: math.MaxInt64 - 1},
+		{target: -1, want: math.MaxInt64},
+	} {
+		value, err = chain.Forward(ctx, "foo", tt.target)
+		if err != nil {
+			t.Fatalf("forward: %s", err)
+		}
+		if value != tt.want {
+			t.Errorf("want forwarded to %d value: %d, got: %d", tt.target, tt.want, value)
+		}
+	}
+
+	value, err = chain.Forward(ctx, "bar", -10)
+	if err != nil {
+		t.Fatalf("forward: %s", err)
+	}
+	if value != -10 {
+		t.Errorf("want forwarded value: -10, got: %d", value)
+	}
+
+	value, err = chain.Forward(ctx, "bar", -20)
+	if err != nil {
+		t.Fatalf("forward: %s", err)
+	}
+	if value != -9 {
+		t.Errorf("want next value: -9, got: %d", value)
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+func newIdempotency(ttl time.Duration) *idempotency {
+	return &idempotency{ttl: ttl, results: make(map[string]*result), expiry: list.New()}
+}
+
+// idempotency holds the results of the requests by the idempotency tokens.
+// The time to live is the same for all of the results, so the expiry list
+// ordered by the insertion is ordered by the expiry time too
+// and the expired results are removed from the front of the list.
+type idempotency struct {
+	sync.Mutex
+	ttl     time.Duration
+	results map[string]*result
+	expiry  *list.List
+}
+
+type result struct {
+	done        chan struct{}
+	token       string
+	fingerprint string
+	expires     time.Time
+	value       int64
+	err         error
+}
+
+// do calls the function once per token within the time to live
+// and returns the result of the first call to the retried requests.
+// The failed calls are not kept, so the retried requests call the function again.
+func (idem *idempotency) do(ctx context.Context, token, fingerprint string, f func() (int64, error)) (int64, error) {
+	now := time.Now()
+
+	idem.Lock()
+
+	if res, ok := idem.results[token]; ok && now.Before(res.expires) {
+		idem.Unlock()
+
+		if res.fingerprint != fingerprint {
+			return 0, invalidf("idempotency key is reused by another request: %s", token)
+		}
+
+		select {
+		case <-res.done:
+			return res.value, res.err
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+
+	for e := idem.expiry.Front(); e != nil; e = idem.expiry.Front() {
+		res := e.Value.(*result)
+		if now.Before(res.expires) {
+			break
+		}
+		idem.expiry.Remove(e)
+		if idem.results[res.token] == res {
+			delete(idem.results, res.token)
+		}
+	}
+
+	res := &result{
+		done:        make(chan struct{}),
+		token:       token,
+		fingerprint: fingerprint,
+		expires:     now.Add(idem.ttl),
+	}
+	idem.results[token] = res
+	idem.expiry.PushBack(res)
+
+	idem.Unlock()
+
+	res.value, res.err = f()
+
+	if res.err != nil {
+		idem.Lock()
+		if idem.results[token] == res {
+			delete(idem.results, token)
+		}
+		idem.Unlock()
+	}
+
+	close(res.done)
+
+	return res.value, res.err
+}
@@ -0,0 +1,350 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package server exposes the serialkey chain over HTTP/JSON.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/pfmt/serialkey"
+	"github.com/pfmt/serialkey/internal/serialkeyhttp"
+)
+
+const (
+	// MaxKeyLength is the maximum length of the key name.
+	MaxKeyLength = 1024
+	// MaxBodySize is the maximum size of the request body.
+	MaxBodySize = 64 << 10
+	// TimeoutHeader is the request header carrying the request timeout
+	// in the Go duration format, for example 1.5s.
+	TimeoutHeader = serialkeyhttp.TimeoutHeader
+	// IdempotencyHeader is the request header carrying the unique token
+	// of the request, the retried request with the same token
+	// returns the result of the first one instead of calling the chain again.
+	IdempotencyHeader = serialkeyhttp.IdempotencyHeader
+)
+
+// Error codes of the error responses.
+const (
+	CodeInvalidRequest   = serialkeyhttp.CodeInvalidRequest
+	CodeNotImplemented   = serialkeyhttp.CodeNotImplemented
+	CodeUnavailable      = serialkeyhttp.CodeUnavailable
+	CodeDeadlineExceeded = serialkeyhttp.CodeDeadlineExceeded
+	CodeCanceled         = serialkeyhttp.CodeCanceled
+	CodeInternal         = serialkeyhttp.CodeInternal
+)
+
+// Request is the body of the requests.
+type Request = serialkeyhttp.Request
+
+// fingerprint returns the fingerprint of the request to the path
+// covering all of the request fields, so the idempotency token
+// reused by the request differing by any field is rejected.
+func fingerprint(path string, req Request) string {
+	return fmt.Sprintf("%s\x00%s\x00%d\x00%d", path, req.Key, req.Count, req.Target)
+}
+
+// Response is the body of the successful responses.
+type Response = serialkeyhttp.Response
+
+// HealthResponse is the body of the successful health check responses.
+type HealthResponse = serialkeyhttp.HealthResponse
+
+// ErrorResponse is the body of the error responses.
+type ErrorResponse = serialkeyhttp.ErrorResponse
+
+// New returns the HTTP/JSON server of the chain.
+func New(chain serialkey.Chain, opts ...Option) *Server {
+	cfg := Configuration{
+		timeout:         10 * time.Second,
+		shutdownTimeout: 10 * time.Second,
+		idempotencyTTL:  10 * time.Minute,
+	}
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	srv := &Server{
+		chain:           chain,
+		timeout:         cfg.timeout,
+		shutdownTimeout: cfg.shutdownTimeout,
+		mux:             http.NewServeMux(),
+		idempotency:     newIdempotency(cfg.idempotencyTTL),
+	}
+
+	srv.mux.HandleFunc("/next", srv.handle(srv.next))
+	srv.mux.HandleFunc("/next-n", srv.handle(srv.nextN))
+	srv.mux.HandleFunc("/last", srv.handle(srv.last))
+	srv.mux.HandleFunc("/forward", srv.handle(srv.forward))
+	srv.mux.HandleFunc("/healthz", srv.healthz)
+
+	return srv
+}
+
+// Server is the HTTP/JSON server exposing the next, the next N,
+// the last and the forward methods of the chain
+// and the health check of the chain backend.
+type Server struct {
+	chain           serialkey.Chain
+	timeout         time.Duration
+	shutdownTimeout time.Duration
+	mux             *http.ServeMux
+	idempotency     *idempotency
+}
+
+// ServeHTTP implements http.Handler.
+func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	srv.mux.ServeHTTP(w, r)
+}
+
+// Handle registers the additional handler for the given pattern.
+func (srv *Server) Handle(pattern string, handler http.Handler) {
+	srv.mux.Handle(pattern, handler)
+}
+
+// ListenAndServe listens on the TCP network address and serves requests
+// until the context is done, then shuts down the server gracefully
+// and closes the chain.
+func (srv *Server) ListenAndServe(ctx context.Context, addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("listen %s: %w", addr, err)
+	}
+	return srv.Serve(ctx, ln)
+}
+
+// Serve serves requests on the listener until the context is done,
+// then shuts down the server gracefully and closes the chain.
+func (srv *Server) Serve(ctx context.Context, ln net.Listener) error {
+	hs := &http.Server{Handler: srv, ReadHeaderTimeout: srv.timeout}
+
+	errs := make(chan error, 1)
+	go func() { errs <- hs.Serve(ln) }()
+
+	var err error
+
+	select {
+	case err = <-errs:
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), srv.shutdownTimeout)
+		defer cancel()
+
+		err = hs.Shutdown(shutdownCtx)
+		if err != nil {
+			err = fmt.Errorf("shutdown: %w", err)
+		}
+	}
+
+	if errors.Is(err, http.ErrServerClosed) {
+		err = nil
+	}
+
+	if e := srv.chain.Close(); e != nil && err == nil {
+		err = fmt.Errorf("close chain: %w", e)
+	}
+
+	return err
+}
+
+func (srv *Server) next(ctx context.Context, req Request) (int64, error) {
+	return srv.chain.Next(ctx, req.Key)
+}
+
+func (srv *Server) nextN(ctx context.Context, req Request) (int64, error) {
+	chain, ok := srv.chain.(serialkey.NextNer)
+	if !ok {
+		return 0, errNotImplemented
+	}
+	if req.Count <= 0 {
+		return 0, invalidf("count must be positive: %d", req.Count)
+	}
+	return chain.NextN(ctx, req.Key, req.Count)
+}
+
+func (srv *Server) last(ctx context.Context, req Request) (int64, error) {
+	return srv.chain.Last(ctx, req.Key)
+}
+
+func (srv *Server) forward(ctx context.Context, req Request) (int64, error) {
+	return srv.chain.Forward(ctx, req.Key, req.Target)
+}
+
+func (srv *Server) handle(method func(context.Context, Request) (int64, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeError(w, http.StatusMethodNotAllowed, CodeInvalidRequest, "method not allowed: "+r.Method)
+			return
+		}
+
+		ctx, cancel, err := srv.context(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+			return
+		}
+		defer cancel()
+
+		var req Request
+
+		dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxBodySize))
+		dec.DisallowUnknownFields()
+
+		err = dec.Decode(&req)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, "decode request: "+err.Error())
+			return
+		}
+
+		if req.Key == "" {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, "missing key")
+			return
+		}
+
+		if len(req.Key) > MaxKeyLength {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, fmt.Sprintf("key is longer than %d bytes", MaxKeyLength))
+			return
+		}
+
+		var value int64
+
+		if token := r.Header.Get(IdempotencyHeader); token != "" {
+			value, err = srv.idempotency.do(ctx, token, fingerprint(r.URL.Path, req), func() (int64, error) {
+				return method(ctx, req)
+			})
+		} else {
+			value, err = method(ctx, req)
+		}
+		if err != nil {
+			status, code := classify(err)
+			writeError(w, status, code, err.Error())
+			return
+		}
+
+		writeJSON(w, http.StatusOK, Response{Key: req.Key, Value: value})
+	}
+}
+
+// healthz responds by the 200 status if the chain backend is healthy
+// and by the 503 status otherwise.
+func (srv *Server) healthz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", http.MethodGet+", "+http.MethodHead)
+		writeError(w, http.StatusMethodNotAllowed, CodeInvalidRequest, "method not allowed: "+r.Method)
+		return
+	}
+
+	ctx, cancel, err := srv.context(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+		return
+	}
+	defer cancel()
+
+	err = serialkey.Health(ctx, srv.chain)
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, CodeUnavailable, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, HealthResponse{Status: "ok"})
+}
+
+// context returns the request context bounded by the server timeout
+// and the request timeout header.
+func (srv *Server) context(r *http.Request) (context.Context, context.CancelFunc, error) {
+	timeout := srv.timeout
+
+	if h := r.Header.Get(TimeoutHeader); h != "" {
+		d, err := time.ParseDuration(h)
+		if err != nil || d <= 0 {
+			return nil, nil, fmt.Errorf("invalid %s header: %q", TimeoutHeader, h)
+		}
+		if timeout <= 0 || d < timeout {
+			timeout = d
+		}
+	}
+
+	if timeout <= 0 {
+		ctx, cancel := context.WithCancel(r.Context())
+		return ctx, cancel, nil
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	return ctx, cancel, nil
+}
+
+var errNotImplemented = fmt.Errorf("method is %w by the chain", serialkey.ErrNotImplemented)
+
+type invalidError struct{ msg string }
+
+func (e invalidError) Error() string { return e.msg }
+
+func (invalidError) Unwrap() error { return serialkey.ErrInvalidRequest }
+
+func invalidf(format string, a ...any) error {
+	return invalidError{msg: fmt.Sprintf(format, a...)}
+}
+
+// classify returns the HTTP status and the error code of the error.
+func classify(err error) (int, string) {
+	switch {
+	case errors.Is(err, serialkey.ErrInvalidRequest):
+		return http.StatusBadRequest, CodeInvalidRequest
+	case errors.Is(err, serialkey.ErrNotImplemented):
+		return http.StatusNotImplemented, CodeNotImplemented
+	case errors.Is(err, serialkey.ErrUnavailable):
+		return http.StatusServiceUnavailable, CodeUnavailable
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, CodeDeadlineExceeded
+	case errors.Is(err, context.Canceled):
+		return http.StatusServiceUnavailable, CodeCanceled
+	default:
+		return http.StatusInternalServerError, CodeInternal
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, code, msg string) {
+	writeJSON(w, status, ErrorResponse{Code: code, Error: msg})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// Option changes configuration.
+type Option func(*Configuration)
+
+// Configuration holds values changeable by options.
+type Configuration struct {
+	timeout         time.Duration
+	shutdownTimeout time.Duration
+	idempotencyTTL  time.Duration
+}
+
+// WithTimeout sets the maximum duration of the chain call,
+// the zero timeout means no limit except the request timeout header.
+func WithTimeout(timeout time.Duration) Option {
+	return func(cfg *Configuration) { cfg.timeout = timeout }
+}
+
+// WithShutdownTimeout sets the maximum duration of the graceful shutdown.
+func WithShutdownTimeout(timeout time.Duration) Option {
+	return func(cfg *Configuration) { cfg.shutdownTimeout = timeout }
+}
+
+// WithIdempotencyTTL sets the duration the results
+// of the requests with the idempotency token are kept.
+func WithIdempotencyTTL(ttl time.Duration) Option {
+	return func(cfg *Configuration) { cfg.idempotencyTTL = ttl }
+}
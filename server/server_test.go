@@ -0,0 +1,384 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pfmt/serialkey"
+	"github.com/pfmt/serialkey/server"
+)
+
+var serverTests = []struct {
+	test   string
+	line   string
+	method string
+	path   string
+	header map[string]string
+	body   string
+	status int
+	want   string
+}{
+	{
+		test:   "next",
+		line:   testline(),
+		method: http.MethodPost,
+		path:   "/next",
+		body:   `{"key":"foo"}`,
+		status: http.StatusOK,
+		want:   `{"key":"foo","value":1}`,
+	}, {
+		test:   "next n",
+		line:   testline(),
+		method: http.MethodPost,
+		path:   "/next-n",
+		body:   `{"key":"bar","count":42}`,
+		status: http.StatusOK,
+		want:   `{"key":"bar","value":42}`,
+	}, {
+		test:   "last",
+		line:   testline(),
+		method: http.MethodPost,
+		path:   "/last",
+		body:   `{"key":"xyz"}`,
+		status: http.StatusOK,
+		want:   `{"key":"xyz","value":0}`,
+	}, {
+		test:   "forward",
+		line:   testline(),
+		method: http.MethodPost,
+		path:   "/forward",
+		body:   `{"key":"abc","target":1000}`,
+		status: http.StatusOK,
+		want:   `{"key":"abc","value":1000}`,
+	}, {
+		test:   "method not allowed",
+		line:   testline(),
+		method: http.MethodGet,
+		path:   "/next",
+		status: http.StatusMethodNotAllowed,
+		want:   `{"code":"invalid_request","error":"method not allowed: GET"}`,
+	}, {
+		test:   "missing key",
+		line:   testline(),
+		method: http.MethodPost,
+		path:   "/next",
+		body:   `{}`,
+		status: http.StatusBadRequest,
+		want:   `{"code":"invalid_request","error":"missing key"}`,
+	}, {
+		test:   "unknown field",
+		line:   testline(),
+		method: http.MethodPost,
+		path:   "/next",
+		body:   `{"key":"foo","foo":1}`,
+		status: http.StatusBadRequest,
+		want:   `{"code":"invalid_request","error":"decode request: json: unknown field \"foo\""}`,
+	}, {
+		test:   "non-positive count",
+		line:   testline(),
+		method: http.MethodPost,
+		path:   "/next-n",
+		body:   `{"key":"foo","count":0}`,
+		status: http.StatusBadRequest,
+		want:   `{"code":"invalid_request","error":"count must be positive: 0"}`,
+	}, {
+		test:   "invalid timeout",
+		line:   testline(),
+		method: http.MethodPost,
+		path:   "/next",
+		header: map[string]string{server.TimeoutHeader: "soon"},
+		body:   `{"key":"foo"}`,
+		status: http.StatusBadRequest,
+		want:   `{"code":"invalid_request","error":"invalid Request-Timeout header: \"soon\""}`,
+	}, {
+		test:   "healthz",
+		line:   testline(),
+		method: http.MethodGet,
+		path:   "/healthz",
+		status: http.StatusOK,
+		want:   `{"status":"ok"}`,
+	}, {
+		test:   "healthz method not allowed",
+		line:   testline(),
+		method: http.MethodPost,
+		path:   "/healthz",
+		status: http.StatusMethodNotAllowed,
+		want:   `{"code":"invalid_request","error":"method not allowed: POST"}`,
+	},
+}
+
+func TestServer(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(server.New(serialkey.NewLocal(serialkey.LocalWithStart(1))))
+	t.Cleanup(ts.Close)
+
+	for _, tt := range serverTests {
+		tt := tt
+
+		t.Run(tt.line+"/"+tt.test, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(tt.method, ts.URL+tt.path, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("new request: %s", err)
+			}
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+
+			resp, err := ts.Client().Do(req)
+			if err != nil {
+				t.Fatalf("do request: %s", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.status {
+				t.Errorf("want status: %d, got: %d", tt.status, resp.StatusCode)
+			}
+
+			var got json.RawMessage
+
+			err = json.NewDecoder(resp.Body).Decode(&got)
+			if err != nil {
+				t.Fatalf("decode response: %s", err)
+			}
+
+			if string(got) != tt.want {
+				t.Errorf("\nwant: %s\ngot:  %s", tt.want, got)
+			}
+		})
+	}
+}
+
+type blocking struct{ serialkey.Chain }
+
+func (blocking) Next(ctx context.Context, _ string) (int64, error) {
+	<-ctx.Done()
+	return 0, ctx.Err()
+}
+
+func TestServerDeadline(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(server.New(blocking{Chain: serialkey.NewLocal()}))
+	defer ts.Close()
+
+	req, err := http.NewRequest(http.MethodPost, ts.URL+"/next", strings.NewReader(`{"key":"foo"}`))
+	if err != nil {
+		t.Fatalf("new request: %s", err)
+	}
+	req.Header.Set(server.TimeoutHeader, "10ms")
+
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatalf("do request: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusGatewayTimeout {
+		t.Errorf("want status: %d, got: %d", http.StatusGatewayTimeout, resp.StatusCode)
+	}
+}
+
+var serverIdempotencyTests = []struct {
+	test   string
+	line   string
+	ttl    time.Duration
+	path   string
+	bodies []string
+	status []int
+	want   []string
+}{
+	{
+		test:   "retried request",
+		line:   testline(),
+		ttl:    time.Minute,
+		path:   "/forward",
+		bodies: []string{`{"key":"foo","target":10}`, `{"key":"foo","target":10}`},
+		status: []int{http.StatusOK, http.StatusOK},
+		want:   []string{`{"key":"foo","value":10}`, `{"key":"foo","value":10}`},
+	}, {
+		test:   "token reused by another target",
+		line:   testline(),
+		ttl:    time.Minute,
+		path:   "/forward",
+		bodies: []string{`{"key":"foo","target":10}`, `{"key":"foo","target":20}`},
+		status: []int{http.StatusOK, http.StatusBadRequest},
+		want: []string{
+			`{"key":"foo","value":10}`,
+			`{"code":"invalid_request","error":"idempotency key is reused by another request: token"}`,
+		},
+	}, {
+		test:   "token reused by another count",
+		line:   testline(),
+		ttl:    time.Minute,
+		path:   "/next-n",
+		bodies: []string{`{"key":"foo","count":2}`, `{"key":"foo","count":3}`},
+		status: []int{http.StatusOK, http.StatusBadRequest},
+		want: []string{
+			`{"key":"foo","value":2}`,
+			`{"code":"invalid_request","error":"idempotency key is reused by another request: token"}`,
+		},
+	}, {
+		test:   "expired token",
+		line:   testline(),
+		ttl:    time.Nanosecond,
+		path:   "/next",
+		bodies: []string{`{"key":"foo"}`, `{"key":"bar"}`, `{"key":"foo"}`},
+		status: []int{http.StatusOK, http.StatusOK, http.StatusOK},
+		want:   []string{`{"key":"foo","value":1}`, `{"key":"bar","value":1}`, `{"key":"foo","value":2}`},
+	},
+}
+
+func TestServerIdempotency(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range serverIdempotencyTests {
+		tt := tt
+
+		t.Run(tt.line+"/"+tt.test, func(t *testing.T) {
+			t.Parallel()
+
+			ts := httptest.NewServer(server.New(
+				serialkey.NewLocal(serialkey.LocalWithStart(1)),
+				server.WithIdempotencyTTL(tt.ttl),
+			))
+			defer ts.Close()
+
+			for i, body := range tt.bodies {
+				req, err := http.NewRequest(http.MethodPost, ts.URL+tt.path, strings.NewReader(body))
+				if err != nil {
+					t.Fatalf("new request: %s", err)
+				}
+				req.Header.Set(server.IdempotencyHeader, "token")
+
+				resp, err := ts.Client().Do(req)
+				if err != nil {
+					t.Fatalf("do request: %s", err)
+				}
+
+				var got json.RawMessage
+
+				err = json.NewDecoder(resp.Body).Decode(&got)
+				resp.Body.Close()
+				if err != nil {
+					t.Fatalf("decode response: %s", err)
+				}
+
+				if resp.StatusCode != tt.status[i] {
+					t.Errorf("request %d: want status: %d, got: %d", i, tt.status[i], resp.StatusCode)
+				}
+
+				if string(got) != tt.want[i] {
+					t.Errorf("request %d:\nwant: %s\ngot:  %s", i, tt.want[i], got)
+				}
+			}
+		})
+	}
+}
+
+type unhealthy struct{ serialkey.Chain }
+
+func (unhealthy) Health(context.Context) error {
+	return errors.New("table serialkeys does not exist")
+}
+
+func TestServerHealthz(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(server.New(unhealthy{Chain: serialkey.NewLocal()}))
+	defer ts.Close()
+
+	resp, err := ts.Client().Get(ts.URL + "/healthz")
+	if err != nil {
+		t.Fatalf("do request: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("want status: %d, got: %d", http.StatusServiceUnavailable, resp.StatusCode)
+	}
+
+	var got server.ErrorResponse
+
+	err = json.NewDecoder(resp.Body).Decode(&got)
+	if err != nil {
+		t.Fatalf("decode response: %s", err)
+	}
+
+	if got.Code != server.CodeUnavailable || got.Error != "table serialkeys does not exist" {
+		t.Errorf("want unavailable error response, got: %+v", got)
+	}
+}
+
+type closing struct {
+	serialkey.Chain
+	closed chan struct{}
+}
+
+func (c closing) Close() error {
+	close(c.closed)
+	return c.Chain.Close()
+}
+
+func TestServerShutdown(t *testing.T) {
+	t.Parallel()
+
+	chain := closing{Chain: serialkey.NewLocal(), closed: make(chan struct{})}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %s", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	errs := make(chan error, 1)
+	go func() { errs <- server.New(chain).Serve(ctx, ln) }()
+
+	resp, err := http.Post("http://"+ln.Addr().String()+"/next", "application/json", strings.NewReader(`{"key":"foo"}`))
+	if err != nil {
+		t.Fatalf("post: %s", err)
+	}
+	resp.Body.Close()
+
+	cancel()
+
+	select {
+	case err := <-errs:
+		if err != nil {
+			t.Errorf("serve: %s", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("want graceful shutdown")
+	}
+
+	select {
+	case <-chain.closed:
+	default:
+		t.Error("want closed chain")
+	}
+}
+
+func testline() string {
+	_, file, line, ok := runtime.Caller(1)
+	if ok {
+		return fmt.Sprintf("%s:%d", filepath.Base(file), line)
+	}
+	return "it was not possible to recover file and line number information about function invocations"
+}
@@ -0,0 +1,277 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package serialkey
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// SnowflakeNodeBits is the number of bits of the node id.
+	SnowflakeNodeBits = 10
+	// SnowflakeCounterBits is the number of bits of the per millisecond counter.
+	SnowflakeCounterBits = 12
+	// SnowflakeNodes is the number of the node ids.
+	SnowflakeNodes = 1 << SnowflakeNodeBits
+
+	snowflakeCounterMask = 1<<SnowflakeCounterBits - 1
+	snowflakeNodeMask    = SnowflakeNodes - 1
+	snowflakeNodeShift   = SnowflakeCounterBits
+	snowflakeTimeShift   = SnowflakeNodeBits + SnowflakeCounterBits
+)
+
+// SnowflakeLeaseKey is the default key name of the leases of the node ids.
+const SnowflakeLeaseKey = "snowflake"
+
+// SnowflakeEpoch is the default epoch of the snowflake timestamps.
+var SnowflakeEpoch = time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// ErrNodeExhausted is returned when there is no free node id.
+var ErrNodeExhausted = errors.New("node ids exhausted")
+
+// ClockRollbackError is returned when the clock moved backwards
+// after the value was issued.
+type ClockRollbackError struct {
+	Key  string
+	Last time.Time
+	Now  time.Time
+}
+
+func (e *ClockRollbackError) Error() string {
+	return fmt.Sprintf("clock moved backwards by %s since the last value %s was issued", e.Last.Sub(e.Now), e.Key)
+}
+
+// NewSnowflake returns the serialkeys keychain issuing the values
+// which packs the timestamp, the node id and the per millisecond counter
+// into the 64-bit integer without the coordination per call.
+func NewSnowflake(opts ...SnowflakeOption) (*Snowflake, error) {
+	cfg := SnowflakeConfiguration{
+		epoch: SnowflakeEpoch,
+		now:   time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if cfg.lease != nil {
+		cfg.node = cfg.lease.ID()
+	}
+
+	if cfg.node < 0 || cfg.node >= SnowflakeNodes {
+		return nil, fmt.Errorf("node id %d is out of range [0,%d)", cfg.node, SnowflakeNodes)
+	}
+
+	return &Snowflake{
+		epoch: cfg.epoch,
+		now:   cfg.now,
+		node:  cfg.node,
+		lease: cfg.lease,
+		table: make(map[string]*snowflakeState),
+	}, nil
+}
+
+// Snowflake is the serialkeys keychain issuing snowflake-style values.
+type Snowflake struct {
+	sync.Mutex
+	epoch time.Time
+	now   func() time.Time
+	node  int64
+	lease *Lease
+	table map[string]*snowflakeState
+}
+
+type snowflakeState struct {
+	millis  int64
+	counter int64
+	last    int64
+}
+
+// Next for the passed key name returns an value guaranteed to be greater
+// than the value returned for the same key name passed at the time
+// of previous call of the next method or the forward method.
+// The next method returns the clock rollback error
+// if the clock moved backwards and the lease lost error
+// if the lease of the node id is lost.
+// The next method is thread safe.
+func (chain *Snowflake) Next(ctx context.Context, key string) (int64, error) {
+	chain.Lock()
+	defer chain.Unlock()
+
+	return chain.next(ctx, key)
+}
+
+func (chain *Snowflake) next(ctx context.Context, key string) (int64, error) {
+	if chain.lease != nil {
+		select {
+		case <-chain.lease.Done():
+			return 0, fmt.Errorf("issue value %s by node %d: %w", key, chain.node, chain.lease.Err())
+		default:
+		}
+	}
+
+	state, ok := chain.table[key]
+	if !ok {
+		state = &snowflakeState{millis: -1}
+		chain.table[key] = state
+	}
+
+	millis := chain.millis()
+
+	if millis < state.millis {
+		return 0, &ClockRollbackError{
+			Key:  key,
+			Last: chain.epoch.Add(time.Duration(state.millis) * time.Millisecond),
+			Now:  chain.epoch.Add(time.Duration(millis) * time.Millisecond),
+		}
+	}
+
+	if millis == state.millis {
+		state.counter = (state.counter + 1) & snowflakeCounterMask
+
+		for state.counter == 0 && millis <= state.millis {
+			if err := ctx.Err(); err != nil {
+				return 0, fmt.Errorf("wait for the next millisecond %s: %w", key, err)
+			}
+			time.Sleep(time.Millisecond / 10)
+			millis = chain.millis()
+		}
+
+	} else {
+		state.counter = 0
+	}
+
+	state.millis = millis
+	state.last = chain.compose(millis, state.counter)
+
+	return state.last, nil
+}
+
+// Last for the passed key name returns the value returned for
+// the same key name passed at the time of previous call
+// of the next method or the forward method.
+// The last method is thread safe.
+func (chain *Snowflake) Last(_ context.Context, key string) (int64, error) {
+	chain.Lock()
+	defer chain.Unlock()
+
+	if state, ok := chain.table[key]; ok {
+		return state.last, nil
+	}
+
+	return 0, nil
+}
+
+// Forward for the passed key name returns an value guaranteed
+// to be greater or equal to the target value and guaranteed to be greater
+// than the value returned for the same key name passed at the time
+// of previous call of the forward method or the next method.
+// The forward method returns an error if the target value
+// is ahead of the clock.
+// The forward method is thread safe.
+func (chain *Snowflake) Forward(ctx context.Context, key string, target int64) (int64, error) {
+	chain.Lock()
+	defer chain.Unlock()
+
+	// The target is checked before the value is issued,
+	// so the rejected target does not consume the counter.
+	if target > chain.compose(chain.millis(), snowflakeCounterMask) {
+		return 0, fmt.Errorf("forward value %s to %d: target is ahead of the clock", key, target)
+	}
+
+	value, err := chain.next(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+
+	// The issued value is less than the target only if the target
+	// is the value of the node within the same millisecond,
+	// so the counter is moved up to the target.
+	if value < target {
+		state := chain.table[key]
+		state.counter = target & snowflakeCounterMask
+		state.last = target
+		value = target
+	}
+
+	return value, nil
+}
+
+// Decompose returns the timestamp, the node id and the counter of the value.
+func (chain *Snowflake) Decompose(value int64) (time.Time, int64, int64) {
+	millis := value >> snowflakeTimeShift
+	node := value >> snowflakeNodeShift & snowflakeNodeMask
+	counter := value & snowflakeCounterMask
+	return chain.epoch.Add(time.Duration(millis) * time.Millisecond), node, counter
+}
+
+// Close do nothing.
+// The close method is thread safe.
+func (*Snowflake) Close() error {
+	return nil
+}
+
+func (chain *Snowflake) millis() int64 {
+	return chain.now().Sub(chain.epoch).Milliseconds()
+}
+
+func (chain *Snowflake) compose(millis, counter int64) int64 {
+	return millis<<snowflakeTimeShift | chain.node<<snowflakeNodeShift | counter
+}
+
+// LeaseSnowflakeNode leases the node id from the pgx pool table,
+// so two nodes sharing the table never share the node id.
+// The lease is renewed until it is closed, the snowflake keychain
+// created by the snowflake with lease option stops issuing the values
+// when the lease is lost.
+// The key name of the leases is the snowflake lease key
+// unless the lease with key option is passed.
+func LeaseSnowflakeNode(ctx context.Context, pool *PgxPool, opts ...LeaseOption) (*Lease, error) {
+	opts = append([]LeaseOption{LeaseWithKey(SnowflakeLeaseKey)}, opts...)
+
+	lease, err := Acquire(ctx, pool, SnowflakeNodes, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("lease snowflake node id: %w", err)
+	}
+
+	return lease, nil
+}
+
+// SnowflakeOption changes configuration.
+type SnowflakeOption func(*SnowflakeConfiguration)
+
+// SnowflakeConfiguration holds values changeable by options.
+type SnowflakeConfiguration struct {
+	epoch time.Time
+	now   func() time.Time
+	node  int64
+	lease *Lease
+}
+
+// SnowflakeWithNode sets the node id.
+func SnowflakeWithNode(node int64) SnowflakeOption {
+	return func(cfg *SnowflakeConfiguration) { cfg.node = node }
+}
+
+// SnowflakeWithEpoch sets the epoch of the timestamps.
+func SnowflakeWithEpoch(epoch time.Time) SnowflakeOption {
+	return func(cfg *SnowflakeConfiguration) { cfg.epoch = epoch }
+}
+
+// SnowflakeWithClock sets the function returning the current time.
+func SnowflakeWithClock(now func() time.Time) SnowflakeOption {
+	return func(cfg *SnowflakeConfiguration) { cfg.now = now }
+}
+
+// SnowflakeWithLease sets the node id leased by the lease,
+// the next and the forward methods return the lease lost error
+// after the lease is lost or closed.
+func SnowflakeWithLease(lease *Lease) SnowflakeOption {
+	return func(cfg *SnowflakeConfiguration) { cfg.lease = lease }
+}
@@ -0,0 +1,263 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package serialkey_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgproto3"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pfmt/serialkey"
+	"github.com/pfmt/serialkey/internal/serialkeytest"
+)
+
+func TestSnowflake(t *testing.T) {
+	chain, err := serialkey.NewSnowflake(serialkey.SnowflakeWithNode(42))
+	if err != nil {
+		t.Fatalf("new snowflake: %s", err)
+	}
+	serialkeytest.Next(t, chain)
+	closer.add(chain.Close)
+}
+
+func TestSnowflakeClockRollback(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	now := time.Date(2022, time.October, 1, 0, 0, 0, 0, time.UTC)
+
+	chain, err := serialkey.NewSnowflake(
+		serialkey.SnowflakeWithNode(7),
+		serialkey.SnowflakeWithClock(func() time.Time { return now }),
+	)
+	if err != nil {
+		t.Fatalf("new snowflake: %s", err)
+	}
+	defer chain.Close()
+
+	value, err := chain.Next(ctx, "foo")
+	if err != nil {
+		t.Fatalf("next: %s", err)
+	}
+
+	at, node, counter := chain.Decompose(value)
+	if !at.Equal(now) || node != 7 || counter != 0 {
+		t.Errorf("want decomposed: %s 7 0, got: %s %d %d", now, at, node, counter)
+	}
+
+	value, err = chain.Next(ctx, "foo")
+	if err != nil {
+		t.Fatalf("next: %s", err)
+	}
+
+	if _, _, counter := chain.Decompose(value); counter != 1 {
+		t.Errorf("want counter within the same millisecond: 1, got: %d", counter)
+	}
+
+	now = now.Add(-time.Second)
+
+	_, err = chain.Next(ctx, "foo")
+
+	var rollback *serialkey.ClockRollbackError
+	if !errors.As(err, &rollback) {
+		t.Fatalf("want clock rollback error, got: %v", err)
+	}
+	if rollback.Last.Sub(rollback.Now) != time.Second {
+		t.Errorf("want rollback: %s, got: %s", time.Second, rollback.Last.Sub(rollback.Now))
+	}
+
+	if _, err = chain.Next(ctx, "bar"); err != nil {
+		t.Errorf("want next value of another key, got: %s", err)
+	}
+}
+
+func TestSnowflakeForwardNotPastLast(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	chain, err := serialkey.NewSnowflake(serialkey.SnowflakeWithNode(42))
+	if err != nil {
+		t.Fatalf("new snowflake: %s", err)
+	}
+
+	last, err := chain.Next(ctx, "foo")
+	if err != nil {
+		t.Fatalf("next: %s", err)
+	}
+
+	for _, target := range []int64{last, last - 10} {
+		value, err := chain.Forward(ctx, "foo", target)
+		if err != nil {
+			t.Fatalf("forward: %s", err)
+		}
+		if value <= last {
+			t.Errorf("want forwarded to %d value greater than: %d, got: %d", target, last, value)
+		}
+		last = value
+	}
+}
+
+func TestSnowflakeForwardAheadOfClock(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	now := time.Date(2022, time.October, 1, 0, 0, 0, 0, time.UTC)
+
+	chain, err := serialkey.NewSnowflake(
+		serialkey.SnowflakeWithNode(7),
+		serialkey.SnowflakeWithClock(func() time.Time { return now }),
+	)
+	if err != nil {
+		t.Fatalf("new snowflake: %s", err)
+	}
+	defer chain.Close()
+
+	_, err = chain.Forward(ctx, "foo", math.MaxInt64)
+	if err == nil {
+		t.Fatal("want target ahead of the clock error")
+	}
+
+	value, err := chain.Next(ctx, "foo")
+	if err != nil {
+		t.Fatalf("next: %s", err)
+	}
+
+	if _, _, counter := chain.Decompose(value); counter != 0 {
+		t.Errorf("want counter not consumed by the rejected forward: 0, got: %d", counter)
+	}
+
+	target := value + 10
+
+	forwarded, err := chain.Forward(ctx, "foo", target)
+	if err != nil {
+		t.Fatalf("forward within the same millisecond: %s", err)
+	}
+
+	if forwarded != target {
+		t.Errorf("want forwarded value: %d, got: %d", target, forwarded)
+	}
+
+	value, err = chain.Next(ctx, "foo")
+	if err != nil {
+		t.Fatalf("next: %s", err)
+	}
+
+	if value != target+1 {
+		t.Errorf("want next value after the forwarded: %d, got: %d", target+1, value)
+	}
+}
+
+func TestSnowflakeNode(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var (
+		mu      sync.Mutex
+		queries []string
+	)
+
+	url := newFakeServer(t, func(backend *pgproto3.Backend, query string) bool {
+		mu.Lock()
+		queries = append(queries, query)
+		mu.Unlock()
+
+		if !strings.HasPrefix(query, "WITH candidate") {
+			backend.Send(&pgproto3.CommandComplete{CommandTag: []byte("DELETE 1")})
+			return true
+		}
+
+		backend.Send(&pgproto3.RowDescription{Fields: []pgproto3.FieldDescription{
+			{Name: []byte("worker"), DataTypeOID: 20, DataTypeSize: 8, TypeModifier: -1},
+			{Name: []byte("available"), DataTypeOID: 16, DataTypeSize: 1, TypeModifier: -1},
+		}})
+		backend.Send(&pgproto3.DataRow{Values: [][]byte{[]byte("5"), []byte("t")}})
+		backend.Send(&pgproto3.CommandComplete{CommandTag: []byte("SELECT 1")})
+
+		return true
+	})
+
+	pool, err := pgxpool.New(ctx, url)
+	if err != nil {
+		t.Fatalf("pgx connect %s: %s", url, err)
+	}
+
+	nodes := serialkey.NewPgxPool(pool, pgxOpt)
+	t.Cleanup(func() { _ = nodes.Close() })
+
+	lease, err := serialkey.LeaseSnowflakeNode(ctx, nodes)
+	if err != nil {
+		t.Fatalf("lease node id: %s", err)
+	}
+
+	if lease.ID() != 5 {
+		t.Errorf("want node id: 5, got: %d", lease.ID())
+	}
+
+	chain, err := serialkey.NewSnowflake(serialkey.SnowflakeWithLease(lease))
+	if err != nil {
+		t.Fatalf("new snowflake: %s", err)
+	}
+
+	value, err := chain.Next(ctx, "foo")
+	if err != nil {
+		t.Fatalf("next: %s", err)
+	}
+
+	if _, node, _ := chain.Decompose(value); node != 5 {
+		t.Errorf("want value of the leased node id: 5, got: %d", node)
+	}
+
+	err = lease.Close()
+	if err != nil {
+		t.Fatalf("close lease: %s", err)
+	}
+
+	_, err = chain.Next(ctx, "foo")
+	if !errors.Is(err, serialkey.ErrLeaseLost) {
+		t.Errorf("want next lease lost error after the lease is closed, got: %v", err)
+	}
+
+	_, err = chain.Forward(ctx, "foo", value+1)
+	if !errors.Is(err, serialkey.ErrLeaseLost) {
+		t.Errorf("want forward lease lost error after the lease is closed, got: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(queries) != 2 {
+		t.Fatalf("want the acquisition and the release queries, got: %q", queries)
+	}
+
+	key := fmt.Sprintf("'%s'", serialkey.SnowflakeLeaseKey)
+	limit := fmt.Sprintf("'%d'", serialkey.SnowflakeNodes)
+	if !strings.Contains(queries[0], key) || !strings.Contains(queries[0], limit) {
+		t.Errorf("want the node id leased by the snowflake key within %d nodes, got query: %s", serialkey.SnowflakeNodes, queries[0])
+	}
+
+	if !strings.HasPrefix(queries[1], "DELETE") {
+		t.Errorf("want the node id released on close, got query: %s", queries[1])
+	}
+
+	_, err = serialkey.NewSnowflake(serialkey.SnowflakeWithNode(serialkey.SnowflakeNodes))
+	if err == nil {
+		t.Error("want out of range node id error")
+	}
+}